@@ -0,0 +1,136 @@
+package bouncer
+
+import (
+	"errors"
+	"time"
+
+	"machine"
+)
+
+const (
+	ERROR_INVALID_ANALOG_BANDS = "Analog bouncer needs at least one band, each with Min <= Max"
+)
+
+// AnalogBand is the range of ADC readings, inclusive, of one button on a resistor ladder
+type AnalogBand struct {
+	Min, Max uint16
+}
+
+// AnalogBouncer recognizes presses on a resistor-ladder keypad, where each button pulls a single ADC pin to a
+// different voltage. The ADC is read on each systick & the reading mapped to the index of the first band
+// containing it, or to no button if it's in none (the no-press band, e.g. near full scale with a pullup). A
+// reading is believed once it maps to the same button on two consecutive systicks, as the voltage passes through
+// other bands while settling; presses are then debounced & recognized as a Group's pins are, with the same Config.
+// Only one button is recognized at a time: going straight from one to another releases the first
+type AnalogBouncer struct {
+	adc      machine.ADC
+	bands    []AnalogBand
+	cls      *bouncer        // holds the AnalogBouncer's Config & classifies the presses of every button
+	raw      int             // the button read on the last systick, or -1
+	key      int             // the button believed to be down, or -1
+	ticks    int             // as ticks in bouncer.RecognizeAndPublish
+	btnDown  time.Time       // as btnDown in bouncer.RecognizeAndPublish
+	tickerCh chan struct{}   // produced by sendTicks -> consumed by RecognizeAndPublish, which reads the ADC
+	outChans []chan KeyEvent // produced by RecognizeAndPublish -> consumed by subscribers of this bouncer's events
+}
+
+// NewAnalogBouncer returns a new AnalogBouncer (or error) reading the given ADC pin, with a band per button &
+// the given channels; a KeyEvent's Key is the index of the button's band. It has the same default durations as New
+func NewAnalogBouncer(p machine.Pin, bands []AnalogBand, outs ...chan KeyEvent) (*AnalogBouncer, error) {
+	if !validPin(p) {
+		return nil, errors.New(ERROR_INVALID_PIN)
+	}
+	if len(bands) < 1 {
+		return nil, errors.New(ERROR_INVALID_ANALOG_BANDS)
+	}
+	for i := range bands {
+		if bands[i].Min > bands[i].Max {
+			return nil, errors.New(ERROR_INVALID_ANALOG_BANDS)
+		}
+	}
+	if len(outs) < 1 {
+		return nil, errors.New(ERROR_NO_OUTPUT_CHANNELS)
+	}
+	a := &AnalogBouncer{
+		adc:      machine.ADC{Pin: p},
+		bands:    make([]AnalogBand, len(bands)),
+		cls:      newBouncer(machine.NoPin),
+		raw:      -1,
+		key:      -1,
+		tickerCh: make(chan struct{}, 1),
+	}
+	copy(a.bands, bands)
+	for i := range outs {
+		a.outChans = append(a.outChans, outs[i])
+	}
+	return a, nil
+}
+
+// Configure applies cfg's durations & Debounce, configures the ADC & subscribes the AnalogBouncer to the
+// systick relay; call machine.InitADC first. ActiveHigh & Pull don't apply. An error configuring the ADC, on the chips
+// reporting one (e.g. rp2040), isn't returned
+func (a *AnalogBouncer) Configure(cfg Config) error {
+	if err := a.cls.apply(cfg); err != nil {
+		return err
+	}
+	a.adc.Configure(machine.ADCConfig{}) // only some chips' Configure returns an error, so it's called as a statement to build on all
+	addSysTickConsumer(a.tickerCh, a.cls.tickSource)
+	return nil
+}
+
+// RecognizeAndPublish should be a goroutine; it reads the ADC on each systick & publishes a KeyEvent for each press
+func (a *AnalogBouncer) RecognizeAndPublish() {
+	for {
+		select {
+		case <-a.tickerCh:
+			if a.ticks > 0 && a.ticks < maxTicks { // only listening during a sequence
+				a.ticks += 1
+			}
+			i := a.button(a.adc.Get())
+			if i == a.raw { // read the same twice running
+				a.sample(i)
+			}
+			a.raw = i
+		}
+	}
+}
+
+// button returns the index of the first band containing an ADC reading, or -1 if it's in none
+func (a *AnalogBouncer) button(v uint16) int {
+	for i := range a.bands {
+		if v >= a.bands[i].Min && v <= a.bands[i].Max {
+			return i
+		}
+	}
+	return -1
+}
+
+// sample handles a believed reading of button i (or -1 for none), as a release of any other & a press of i
+func (a *AnalogBouncer) sample(i int) {
+	if i == a.key {
+		return
+	}
+	if a.key >= 0 { // sequence concluded, unless it hasn't outlasted the debounce interval
+		if !a.cls.debounced(a.ticks, a.btnDown) {
+			return
+		}
+		dur := a.cls.now().Sub(a.btnDown)
+		if l := a.cls.recognize(dur); l != Bounce || a.cls.publishBounces { // too short to be a press
+			a.publish(KeyEvent{Key: a.key, Length: l})
+		}
+		a.key, a.ticks, a.btnDown = -1, 0, time.Time{}
+	}
+	if i >= 0 { // sequence began
+		a.key, a.ticks, a.btnDown = i, 1, a.cls.now()
+	}
+}
+
+// publish sends a KeyEvent to all channels subscribed to this AnalogBouncer
+func (a *AnalogBouncer) publish(e KeyEvent) {
+	for i := range a.outChans {
+		select {
+		case a.outChans[i] <- e:
+		default:
+		}
+	}
+}
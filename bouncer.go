@@ -4,7 +4,13 @@
 package bouncer
 
 import (
+	"context"
 	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"machine"
@@ -13,8 +19,33 @@ import (
 const (
 	ERROR_INVALID_PRESSLENGTH = "PressLength not understood"
 	ERROR_NO_OUTPUT_CHANNELS  = "New bouncer wasn't given any output channels"
+	ERROR_OUTPUT_NOT_FOUND    = "Channel is not an output of this bouncer"
+	ERROR_DUPLICATE_OUTPUT    = "Channel is already an output of this bouncer"
+	ERROR_NIL_OUTPUT_CHANNEL  = "Output channel is nil"
+	ERROR_NIL_CALLBACK        = "Callback is nil"
+	ERROR_CALLBACK_NOT_FOUND  = "Callback is not registered with this bouncer"
+	ERROR_INVALID_PULL        = "Pull not understood"
+	ERROR_INVALID_EDGES       = "Edges not understood"
+	ERROR_UNBUFFERED_OUTPUT   = "Coalescing output channel must be buffered"
+	ERROR_DROPPED_EDGES       = "Pin interrupts were dropped because isrChan was full"
+	ERROR_MISSED_UP_EDGE      = "Up edge was missed; the press was published as Timeout"
+	ERROR_SLEPT_DURING_PRESS  = "Press spanned an MCU sleep & was discarded"
+	ERROR_INVALID_BANDS       = "Bands must have positive & distinct Min durations"
+	ERROR_WAIT_TIMEOUT        = "No press before the timeout"
+	ERROR_STOPPED             = "Bouncer was stopped"
+	ERROR_INVALID_PIN         = "Pin is not usable (nil or machine.NoPin)"
+	ERROR_STUCK               = "Button has been held for StuckThreshold & is probably stuck"
+	ERROR_IMPOSSIBLE_DURATION = "Press measured an impossible duration, e.g. from a stale press start, & was discarded"
+	ERROR_NO_DEBOUNCE         = "Debounce isn't running, so no systicks are relayed & no presses recognized"
+	ERROR_INVALID_DEPTH       = "Buffered output depth must be positive"
 )
 
+// maxTicks is where a press's ticks stop counting. Were they to keep on, a button held (or stuck) for 2^31
+// systicks (under 6 hours of a 100kHz systick) would overflow ticks on a 32-bit MCU: negative ticks never
+// debounce its release, and ticks eventually wraps to 0, abandoning the press with its btnDown still set.
+// Only whether ticks has reached 2 matters to debounced anyway
+const maxTicks = 1 << 30
+
 type PressLength uint8
 
 const (
@@ -22,189 +53,2267 @@ const (
 	ShortPress
 	LongPress
 	ExtraLongPress
+	DoubleClick
+	PressStarted
+	Repeat
+	HeldShortPress     // the button, still held, has crossed the ShortPress threshold
+	HeldLongPress      // the button, still held, has crossed the LongPress threshold
+	HeldExtraLongPress // the button, still held, has crossed the ExtraLongPress threshold
+	Timeout            // the button was found released without an up edge having been seen
+	MultiClick         // three or more ShortPresses in quick succession; see Event.Clicks
+	Tap                // shorter than a ShortPress, but longer than Config.Tap; for e.g. capacitive buttons
+	Armed              // with Config.ArmFire, the held button has reached Long
+	Fired              // with Config.ArmFire, the button was released after being Armed
+	Aborted            // with Config.ArmFire, the button was released before being Armed
+	Stuck              // the button has been held for Config.StuckThreshold, so is probably stuck or shorted
+	SwitchedOn         // with Config.Latching, the switch has closed
+	SwitchedOff        // with Config.Latching, the switch has opened
+)
+
+// String returns the name of the PressLength, e.g. for logging, or PressLength(n) for a value without one, such
+// as a custom band's Label
+func (l PressLength) String() string {
+	switch l {
+	case Bounce:
+		return "Bounce"
+	case ShortPress:
+		return "ShortPress"
+	case LongPress:
+		return "LongPress"
+	case ExtraLongPress:
+		return "ExtraLongPress"
+	case DoubleClick:
+		return "DoubleClick"
+	case PressStarted:
+		return "PressStarted"
+	case Repeat:
+		return "Repeat"
+	case HeldShortPress:
+		return "HeldShortPress"
+	case HeldLongPress:
+		return "HeldLongPress"
+	case HeldExtraLongPress:
+		return "HeldExtraLongPress"
+	case Timeout:
+		return "Timeout"
+	case MultiClick:
+		return "MultiClick"
+	case Tap:
+		return "Tap"
+	case Armed:
+		return "Armed"
+	case Fired:
+		return "Fired"
+	case Aborted:
+		return "Aborted"
+	case Stuck:
+		return "Stuck"
+	case SwitchedOn:
+		return "SwitchedOn"
+	case SwitchedOff:
+		return "SwitchedOff"
+	}
+	return "PressLength(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Pull selects how a bouncer's pin is configured; machine.PinInput is zero on some targets, so a
+// machine.PinMode in Config couldn't tell "unset" apart from "no pull"
+type Pull uint8
+
+const (
+	PullDefault Pull = iota // InputPullup, or InputPulldown if ActiveHigh
+	PullUp                  // InputPullup
+	PullDown                // InputPulldown
+	PullNone                // plain Input, for buttons with strong external pull resistors
+)
+
+// Edges selects which of the pin's edges interrupt; an edge that doesn't is found by sampling the pin on each systick
+type Edges uint8
+
+const (
+	BothEdges   Edges = iota // interrupt on every edge
+	FallingEdge              // interrupt on falling edges only, e.g. an active-low button's press
+	RisingEdge               // interrupt on rising edges only, e.g. an active-high button's press
 )
 
+// InputPin is the part of machine.Pin a bouncer uses; machine.Pin satisfies it, and tests can
+// substitute a fake such as bouncertest.FakePin via NewWithPin
+type InputPin interface {
+	Configure(machine.PinConfig)
+	Get() bool
+	SetInterrupt(machine.PinChange, func(machine.Pin)) error
+}
+
+// Band is a custom duration band for WithBands: a press at least Min long (but shorter than the next band's Min)
+// is published as PressLength(Label)
+type Band struct {
+	Min   time.Duration
+	Label uint8
+}
+
+// Clock tells a bouncer the time; the real clock by default, but a test can inject a fake one via Config.Clock
+type Clock interface {
+	Now() time.Time
+}
+
+// ButtonState is whether a button is currently held down, accounting for how it's wired
+type ButtonState uint8
+
+const (
+	Released ButtonState = iota
+	Pressed
+)
+
+// Event is a recognized PressLength along with when the press began & how long it actually lasted.
+// Every event a bouncer publishes takes the next Seq, so a subscriber seeing a gap knows it missed an event;
+// a Seq lower than the last means the bouncer was reconfigured or stopped in between
+type Event struct {
+	Length   PressLength
+	Duration time.Duration
+	At       time.Time     // when the press began, i.e. its down edge
+	End      time.Time     // when the press was released, i.e. its up edge; zero for events published while held
+	Clicks   int           // with click counting configured, how many clicks made up a ShortPress, DoubleClick or MultiClick
+	Seq      uint32        // numbers the bouncer's published events from 1, restarting when it's configured or stopped
+	Release  time.Duration // of a press classified on release, how long the contact was last steadily closed; see RecognizeAndPublish
+	Press    uint32        // numbers the bouncer's presses from 1; the events of one press (& its phases) carry the same Press
+}
+
+// SourcedPress is a PressLength published to an AddSourcedOutput channel, with the bouncer that published it
+type SourcedPress struct {
+	ID     string // the Source's ID, stable across reboots
+	Source Bouncer
+	Length PressLength
+}
+
+// Stats counts what a bouncer has seen since boot
+type Stats struct {
+	Presses  map[PressLength]uint32 // published events, by PressLength
+	Rejected uint32                 // edges ignored for arriving within Debounce, ReleaseSettle or InterPressGap, & presses shorter than MinPress
+	Dropped  uint32                 // events a reliable output didn't accept within its timeout, or a buffered output had no room for
+}
+
+// BandTiming summarizes the measured durations of the presses classified as one PressLength, for tuning thresholds
+type BandTiming struct {
+	Count         uint32
+	Min, Max, Avg time.Duration
+}
+
+// bandTiming accumulates a BandTiming
+type bandTiming struct {
+	count         uint32
+	min, max, sum time.Duration
+}
+
+// output is a channel subscribed to a bouncer's events, and how long publish may block sending to it
+type output struct {
+	ch       chan PressLength
+	timeout  time.Duration // zero is best-effort: the event is dropped if ch can't take it straight away
+	priority bool          // sent to before the other outputs, blocking until ch takes the event
+	filter   []PressLength // if any, ch only receives these
+	coalesce bool          // when ch is full, its oldest pending event is discarded to make room
+	queue    *outputQueue  // if set, events ch can't take straight away wait here to be drained
+}
+
+// outputQueue holds up to depth events for a buffered output, oldest first
+type outputQueue struct {
+	events []PressLength
+	depth  int
+}
+
+type pressCallback struct {
+	id int
+	fn func(PressLength)
+}
+
+type stateCallback struct {
+	id int
+	fn func(pressed bool)
+}
+
+// thresholds are the durations & bands a press is classified by
+type thresholds struct {
+	tap, short, long, extraLong time.Duration
+	bands                       []Band // longest first; WithBands replaces the slice rather than modifying it
+}
+
+// concludesPress reports whether l concludes a press, rather than announcing one still in progress
+// (PressStarted, Repeat, the Held* threshold crossings, Armed, SwitchedOn or Stuck) or being too short for one
+// (Bounce)
+func concludesPress(l PressLength) bool {
+	switch l {
+	case PressStarted, Repeat, HeldShortPress, HeldLongPress, HeldExtraLongPress, Armed, SwitchedOn, Stuck, Bounce:
+		return false
+	}
+	return true
+}
+
+// configRequest carries a Config from Configure to a running RecognizeAndPublish, & the error applying it back
+type configRequest struct {
+	cfg Config
+	err chan error
+}
+
 type sysTickSubscriber struct {
 	channel chan struct{}
+	source  string // the tag of the tick source relayed to channel; "" is the one given to Debounce
 }
 
-var sysTickSubcribers []sysTickSubscriber
+var (
+	sysTickSubcribers []sysTickSubscriber
+	sysTickMu         sync.RWMutex // guards sysTickSubcribers, which is ranged over by the Debounce goroutine
+	relaysRunning     int32        // how many relay goroutines (Debounce & co.) are running, for DebounceRunning
+)
+
+// debounceGrace is how long after Configure a bouncer with Faults waits for a relay before reporting ERROR_NO_DEBOUNCE
+const debounceGrace = time.Second
+
+// latchingSettle is the ReleaseSettle of a Latching bouncer without one: a switch's contacts chatter as it opens,
+// & a bounce lasting a systick would otherwise be debounced as switching it back on
+const latchingSettle = 20 * time.Millisecond
 
 type Config struct {
+	Tap       time.Duration // presses at least this long but shorter than Short are Tap, not Bounce; zero disables
 	Short     time.Duration
 	Long      time.Duration
 	ExtraLong time.Duration
+	DoubleGap time.Duration // max gap between two ShortPresses to be published as one DoubleClick; zero disables
+	ClickGap  time.Duration // like DoubleGap, but counts any number of ShortPresses, publishing MultiClick for 3+
+	Debounce  time.Duration // min time between down & up for the up to be believed; zero means one systick
+	MinPress  time.Duration // completed presses shorter than this are rejected as ghosts, as Bounce; zero disables
+	Cooldown  time.Duration // an event this soon after the last of the same PressLength is suppressed; zero disables
+
+	ReleaseSettle time.Duration // min time between a believed up & the next down for the down to be believed; zero disables, except with Latching
+	InterPressGap time.Duration // min time between a press being published & the next down for the down to be believed; zero disables
+
+	TickPeriod time.Duration // the systick's period, if known; without a Debounce, an up edge is believed after this long
+
+	NotifyPressStarted bool // publish PressStarted as soon as the button goes down
+
+	RepeatDelay    time.Duration // how long the button must be held before the first Repeat
+	RepeatInterval time.Duration // cadence of Repeat events while the button remains held; zero disables
+
+	ActiveHigh bool // button pulls the pin high when pressed; uses InputPulldown instead of InputPullup
+	Pull       Pull // overrides the pin mode chosen by ActiveHigh, without changing its interpretation
+
+	NotifyThresholds bool          // publish HeldShortPress etc. as a held button crosses each threshold
+	Hysteresis       time.Duration // a threshold is only crossed once the hold is this far past it; see RecognizeAndPublish
+
+	PublishBounces bool // publish Bounce for up edges rejected by debouncing & presses shorter than Short
+
+	ISRBufferSize  int // how many pin interrupts can queue up for RecognizeAndPublish; zero means 3
+	TickBufferSize int // how many systicks can queue up for RecognizeAndPublish; zero means 1
+	Recent         int // how many of the latest published Events to keep for Recent; zero (the default) keeps none
+
+	Integrator int   // debounce by sampling the pin each systick instead of interrupting; see RecognizeAndPublish
+	Poll       bool  // detect edges by sampling the pin each systick, for pins without interrupt support
+	Edges      Edges // which edges interrupt, halving interrupts if only one does; see RecognizeAndPublish
+
+	ExclusiveBounds bool // a press lasting exactly a threshold takes the band below it, rather than the band above
+
+	SleepGap       time.Duration // a gap in systicks longer than this means the MCU slept; see RecognizeAndPublish
+	StuckThreshold time.Duration // a press held this long is published once as Stuck & not classified; zero disables
+
+	LongPressOnThreshold bool // publish LongPress as soon as a held button reaches Long, & on release only ExtraLongPress
+	TapOrHold            bool // publish exactly one of ShortPress (a tap) or LongPress (a hold) per press; see RecognizeAndPublish
+	Latching             bool // for a maintained switch: publish SwitchedOn & SwitchedOff, never classifying durations; see RecognizeAndPublish
+	Cumulative           bool // a longer press doesn't cancel the shorter bands' events, e.g. ShortPress then LongPress; see RecognizeAndPublish
+	ArmFire              bool // "hold to arm, release to fire": publish Armed, Fired & Aborted instead; see RecognizeAndPublish
+
+	Clock Clock // if set, the bouncer times presses by this rather than time.Now, e.g. for deterministic tests
+
+	Classify func(time.Duration) PressLength // if set, classifies each released press instead of the durations or bands
+
+	TickSource string // the tag of the tick source, given to DebounceSources, this bouncer counts; "" is Debounce's
+
+	Faults chan error // if set, runtime faults are reported here, without blocking; see RecognizeAndPublish & DebounceRunning
 }
 
 type bouncer struct {
-	pin              *machine.Pin
+	name             string // identifies this bouncer in logs & metrics
+	cfg              Config // as last applied, for GetConfig
+	pin              InputPin
+	now              func() time.Time // time.Now, Config.Clock's Now, or the clock of a trace in Replay
 	debounceInterval time.Duration
+	releaseSettle    time.Duration
+	interPressGap    time.Duration
+	lastRelease      time.Time // when the last believed up edge was handled, for Config.ReleaseSettle
+	lastConcluded    time.Time // when the last press was published, for Config.InterPressGap
+	downRejected     bool      // a down edge was ignored by downBlocked
+	minPress         time.Duration
+	cooldown         time.Duration
+	tickPeriod       time.Duration
+	tap              time.Duration
 	shortPress       time.Duration
 	longPress        time.Duration
 	extraLongPress   time.Duration
-	tickerCh         chan struct{}      // produced by sendTicks (relaying systick_handler ticks) -> consumed by RecognizeAndPublish (listening for ticks)
-	isrChan          chan bool          // produced by the pin interrupt handler -> consumed by RecognizeAndPublish
-	outChans         []chan PressLength // various channels produced by RecognizeAndPublish -> consumed by subscribers of this bouncer's events
+	bands            []Band       // custom bands from WithBands, sorted by descending Min; replace the durations above if any
+	durMu            sync.RWMutex // guards tap, shortPress, longPress, extraLongPress, bands, activeHigh & cfg, which may be changed by SetDurations, WithBands, SetPolarity & Configure
+	doubleGap        time.Duration
+	multiClickGap    time.Duration
+	notifyStart      bool
+	repeatDelay      time.Duration
+	repeatInterval   time.Duration
+	notifyHeld       bool
+	hysteresis       time.Duration
+	publishBounces   bool
+	integratorMax    int
+	poll             bool
+	edges            Edges
+	tickSource       string
+	classify         func(time.Duration) PressLength
+	exclusiveBounds  bool
+	sleepGap         time.Duration
+	stuckThreshold   time.Duration
+	longOnThreshold  bool
+	cumulative       bool
+	latching         bool
+	tapOrHold        bool
+	armFire          bool
+	faults           chan error
+	reportedDrops    uint32 // droppedEdges as of the last ERROR_DROPPED_EDGES fault; owned by RecognizeAndPublish
+	pull             Pull
+	activeHigh       bool                      // pin reads high while the button is down
+	tickerCh         chan struct{}             // produced by sendTicks (relaying systick_handler ticks) -> consumed by RecognizeAndPublish (listening for ticks)
+	isrChan          chan bool                 // produced by the pin interrupt handler -> consumed by RecognizeAndPublish
+	seq              uint32                    // Seq of the last published Event; accessed atomically
+	droppedEdges     uint32                    // interrupts dropped because isrChan was full; accessed atomically
+	paused           uint32                    // 1 between Pause & Resume; accessed atomically
+	configured       bool                      // Configure has succeeded at least once
+	btnDown          time.Time                 // beginning time of the press in progress, zero between presses
+	seqMu            sync.Mutex                // guards btnDown, which is written on the RecognizeAndPublish goroutine
+	ticks            int                       // begins to increment when a button 'down' is registered; owned by RecognizeAndPublish, as are the below
+	repeats          int                       // Repeat events published during the press in progress
+	crossed          PressLength               // the longest threshold the press in progress has crossed while held
+	th               thresholds                // the durations & bands as the press in progress began, which it's classified by
+	firedLong        bool                      // the press in progress was published as LongPress on reaching its threshold
+	armed            bool                      // with ArmFire, the press in progress has reached Long
+	stuck            bool                      // the press in progress has reached StuckThreshold & was published as Stuck
+	announced        bool                      // the press in progress has outlasted the debounce interval & was reported to OnStateChange
+	lastEdgeDown     bool                      // the last edge handled was 'down', even if it was ignored
+	clicks           int                       // ShortPresses held back awaiting further clicks
+	clickAt          time.Time                 // when the last held-back click was recognized
+	integrator       int                       // pin samples in favour of 'down', from 0 to integratorMax
+	integratedDown   bool                      // the integrator last saturated 'down'
+	polledLevel      bool                      // the pin level last sampled in Poll mode, or seen by a single-edge interrupt
+	lastTick         time.Time                 // when the last systick was received
+	firstClick       Event                     // the first held-back click
+	lastDownEdge     time.Time                 // when the last 'down' edge was handled, even if it was ignored
+	lastPublished    map[PressLength]time.Time // when each PressLength was last published, for Cooldown
+	single           chan PressLength          // the only output, if it's a plain AddOutput one, for publish's fast path
+	outputs          []output                  // various channels produced by RecognizeAndPublish -> consumed by subscribers of this bouncer's events
+	eventChans       []chan Event              // like outputs, for subscribers wanting the whole Event
+	mappedOuts       []func(Event)             // like outputs, sending a NewMapped bouncer's own type
+	sourcedChans     []chan SourcedPress       // like outputs, for channels shared by several bouncers
+	phaseChans       []chan Event              // produced as each press begins & ends -> consumed by AddPhaseOutput subscribers
+	press            uint32                    // numbers the presses, for Event.Press
+	callbacks        []pressCallback           // functions invoked by publish on the RecognizeAndPublish goroutine
+	stateCallbacks   []stateCallback           // functions invoked as a press begins & ends, on the RecognizeAndPublish goroutine
+	nextCallbackID   int
+	snapOutputs      []output // publish's snapshot of outputs, reused between events; owned by RecognizeAndPublish, as are the below
+	snapEventChans   []chan Event
+	snapSourcedChans []chan SourcedPress
+	outMu            sync.Mutex // guards single, outputs, eventChans, mappedOuts, sourcedChans, phaseChans, callbacks & stateCallbacks, which are read by publish on the RecognizeAndPublish goroutine
+	stats            Stats
+	recent           []Event                     // ring buffer of the latest published Events, for Recent
+	timing           map[PressLength]*bandTiming // measured durations of presses classified on release, for Timing
+	recentNext       int                         // where in recent the next Event goes
+	recentCount      int                         // how many Events recent holds
+	lastPress        PressLength                 // the latest published event, for LastPress
+	lastPressAt      time.Time                   // when lastPress was published; zero until the first
+	statsMu          sync.Mutex                  // guards stats, recent, timing & lastPress, which are written on the RecognizeAndPublish goroutine
+	done             chan struct{}               // closed by Stop -> consumed by RecognizeAndPublish, which returns
+	resetCh          chan struct{}               // produced by Reset -> consumed by RecognizeAndPublish, which discards the press in progress
+	cfgCh            chan configRequest          // produced by Configure while RecognizeAndPublish runs -> consumed by RecognizeAndPublish, which applies it
+	running          bool                        // RecognizeAndPublish has started, so Configure hands it the Config rather than applying it alongside
+	runMu            sync.Mutex                  // guards running
+	stopOnce         sync.Once
 }
 
 type Bouncer interface {
 	Configure(Config) error
+	SetDurations(Config) error
+	SetPolarity(activeHigh bool)
+	WithBands([]Band) error
+	GetConfig() Config
 	RecognizeAndPublish()
-	State() bool
+	State() ButtonState
+	Pressed() bool
 	Duration(PressLength) time.Duration
+	DebounceInterval() time.Duration
+	TickPeriod() time.Duration
+	DroppedEdges() uint32
+	AddOutput(chan PressLength) error
+	AddReliableOutput(chan PressLength, time.Duration) error
+	AddPriorityOutput(chan PressLength) error
+	AddFilteredOutput(chan PressLength, ...PressLength) error
+	AddCoalescingOutput(chan PressLength) error
+	AddBufferedOutput(chan PressLength, int) error
+	RemoveOutput(chan PressLength) error
+	AddSourcedOutput(chan SourcedPress) error
+	RemoveSourcedOutput(chan SourcedPress) error
+	AddPhaseOutput(chan Event) error
+	RemovePhaseOutput(chan Event) error
+	OnPress(func(PressLength)) (int, error)
+	WaitForPress(time.Duration) (PressLength, error)
+	RemoveOnPress(int) error
+	OnStateChange(func(pressed bool)) (int, error)
+	RemoveOnStateChange(int) error
+	Stop()
+	Reset()
+	Pause()
+	Resume()
+	Name() string
+	ID() string
+	Pin() machine.Pin
+	Stats() Stats
+	Recent() []Event
+	LastPress() (PressLength, time.Time, bool)
+	Timing() map[PressLength]BandTiming
+	HeldFor() time.Duration
+	InProgress() bool
 }
 
-// New returns a new Bouncer (or error) with the given pin, name & channels, with default durations for
-// shortPress, longPress, extraLongPress
+// New returns a new Bouncer (or error) with the given pin & channels, with default durations for
+// shortPress, longPress, extraLongPress. Channels are optional: a bouncer without outputs still recognizes
+// presses for OnPress callbacks, Stats & Recent, & outputs can be added later
 func New(p machine.Pin, outs ...chan PressLength) (Bouncer, error) {
+	return NewWithPin(p, outs...)
+}
+
+// NewWithPin is like New, but takes any InputPin, e.g. a fake pin driven by a test
+func NewWithPin(p InputPin, outs ...chan PressLength) (Bouncer, error) {
+	if !validPin(p) {
+		return nil, errors.New(ERROR_INVALID_PIN)
+	}
+	b := newBouncer(p)
+	b.outputs = make([]output, 0, len(outs))
+	for i := range outs {
+		if !b.hasOutput(outs[i]) { // a channel passed twice is only sent to once
+			b.outputs = append(b.outputs, output{ch: outs[i]})
+		}
+	}
+	b.outputsChanged()
+	return b, nil
+}
+
+// NewNamed is like New, but gives the Bouncer a name for telling it apart in logs & metrics
+func NewNamed(name string, p machine.Pin, outs ...chan PressLength) (Bouncer, error) {
+	b, err := New(p, outs...)
+	if err != nil {
+		return nil, errors.New(name + ": " + err.Error())
+	}
+	b.(*bouncer).name = name
+	return b, nil
+}
+
+// Start is New, Configure & go RecognizeAndPublish in one, for quick prototypes; the returned Bouncer is
+// already recognizing, so don't start another RecognizeAndPublish. Debounce must still be running
+func Start(p machine.Pin, cfg Config, outs ...chan PressLength) (Bouncer, error) {
+	b, err := New(p, outs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Configure(cfg); err != nil {
+		return nil, err
+	}
+	go b.RecognizeAndPublish()
+	return b, nil
+}
+
+// ConfigureAll configures each of the bouncers with cfg, e.g. the many identical keys of a keyboard. A bouncer
+// that fails doesn't stop the rest being configured; the error returned lists each failure by its index in bouncers
+func ConfigureAll(cfg Config, bouncers ...Bouncer) error {
+	var failures []string
+	for i := range bouncers {
+		if err := bouncers[i].Configure(cfg); err != nil {
+			failures = append(failures, "bouncer "+strconv.Itoa(i)+": "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// NewWithEvents is like New, but its channels receive the whole Event rather than just the PressLength
+func NewWithEvents(p machine.Pin, outs ...chan Event) (Bouncer, error) {
+	if !validPin(p) {
+		return nil, errors.New(ERROR_INVALID_PIN)
+	}
+	b := newBouncer(p)
+	for i := range outs {
+		if !b.hasEventChan(outs[i]) { // a channel passed twice is only sent to once
+			b.eventChans = append(b.eventChans, outs[i])
+		}
+	}
+	return b, nil
+}
+
+// NewMapped is like New, but its channels receive mapFn's value for each published event's PressLength & Duration,
+// e.g. an app's own command type, rather than the PressLength. mapFn runs on the RecognizeAndPublish goroutine, so
+// it must return quickly. New remains the constructor for plain PressLength channels
+func NewMapped[T any](p machine.Pin, mapFn func(PressLength, time.Duration) T, outs ...chan T) (Bouncer, error) {
+	if !validPin(p) {
+		return nil, errors.New(ERROR_INVALID_PIN)
+	}
+	if mapFn == nil {
+		return nil, errors.New(ERROR_NIL_CALLBACK)
+	}
 	if len(outs) < 1 {
 		return nil, errors.New(ERROR_NO_OUTPUT_CHANNELS)
 	}
-	outChans := make([]chan PressLength, 0)
+	chans := make([]chan T, 0, len(outs))
 	for i := range outs {
-		outChans = append(outChans, outs[i])
+		if outs[i] == nil {
+			return nil, errors.New(ERROR_NIL_OUTPUT_CHANNEL)
+		}
+		dup := false
+		for j := range chans {
+			dup = dup || chans[j] == outs[i]
+		}
+		if !dup { // a channel passed twice is only sent to once
+			chans = append(chans, outs[i])
+		}
+	}
+	b := newBouncer(p)
+	b.mappedOuts = append(b.mappedOuts, func(e Event) {
+		v := mapFn(e.Length, e.Duration)
+		for i := range chans {
+			select {
+			case chans[i] <- v:
+			default:
+			}
+		}
+	})
+	return b, nil
+}
+
+// validPin reports whether p can be used by a bouncer
+func validPin(p InputPin) bool {
+	if mp, ok := p.(machine.Pin); ok {
+		return mp != machine.NoPin
 	}
+	return p != nil
+}
+
+// newBouncer returns a bouncer with default durations and no outputs
+func newBouncer(p InputPin) *bouncer {
 	return &bouncer{
-		pin:            &p,
+		pin:            p,
+		now:            time.Now,
 		shortPress:     22 * time.Millisecond,
 		longPress:      500 * time.Millisecond,
 		extraLongPress: 1971 * time.Millisecond,
 		tickerCh:       make(chan struct{}, 1),
 		isrChan:        make(chan bool, 3), // Buffer interrupts during rapid bouncing
-		outChans:       outChans,
-	}, nil
+		done:           make(chan struct{}),
+		resetCh:        make(chan struct{}, 1),
+		cfgCh:          make(chan configRequest),
+	}
 }
 
-// Configure sets the pin mode to InputPullup, assigns interrupt handler, overrides default durations
+// Configure sets the pin mode to InputPullup (or InputPulldown if ActiveHigh, or as set by Pull), assigns
+// interrupt handler, overrides default durations; zero-valued durations in cfg leave the corresponding default in place.
+// Returns an error without touching the pin if the resulting durations aren't 0 < Short <= Long <= ExtraLong,
+// or a Tap isn't shorter than Short.
+// Configuring again replaces the previous configuration & interrupt handler, keeping the one systick
+// subscription; ISRBufferSize & TickBufferSize are only honoured the first time, as RecognizeAndPublish may be
+// reading isrChan & tickerCh. While RecognizeAndPublish is running, the new settings are handed to its goroutine
+// & applied between events, so Configure waits for it (& returns ERROR_STOPPED if the bouncer is stopped).
+// Should setting the interrupt fail, the bouncer is left unconfigured - its interrupt cleared, unsubscribed from
+// the systick relay & its previous settings (and pin mode, if it had one) restored - rather than half-configured
 func (b *bouncer) Configure(cfg Config) error {
-	b.pin.Configure(machine.PinConfig{Mode: machine.PinInputPullup})
-	err := b.pin.SetInterrupt(machine.PinFalling|machine.PinRising, func(machine.Pin) {
-		select {
-		case b.isrChan <- b.pin.Get():
-		default:
+	prev, wasConfigured := b.GetConfig(), b.configured
+	if err := b.applyConfig(cfg); err != nil {
+		return err
+	}
+	b.pin.Configure(machine.PinConfig{Mode: b.pinMode()})
+	if cfg.ISRBufferSize > 0 && !b.configured {
+		b.isrChan = make(chan bool, cfg.ISRBufferSize)
+	}
+	if cfg.TickBufferSize > 0 && !b.configured { // before tickerCh is subscribed to the relay
+		b.tickerCh = make(chan struct{}, cfg.TickBufferSize)
+	}
+	var err error
+	if b.sampled() { // the pin is sampled on each systick rather than interrupting
+		if b.configured {
+			err = b.pin.SetInterrupt(0, nil)
 		}
-	})
-	if err != nil {
+	} else {
+		err = b.pin.SetInterrupt(b.pinChange(), func(machine.Pin) {
+			select {
+			case b.isrChan <- b.pin.Get():
+			default:
+				atomic.AddUint32(&b.droppedEdges, 1)
+			}
+		})
+	}
+	if err != nil { // roll back, so Configure can simply be retried
+		b.pin.SetInterrupt(0, nil)
+		removeSysTickConsumer(b.tickerCh)
+		b.configured = false
+		b.applyConfig(prev)
+		if wasConfigured {
+			b.pin.Configure(machine.PinConfig{Mode: b.pinMode()})
+		}
+		return b.newError(err.Error())
+	}
+	addSysTickConsumer(b.tickerCh, b.tickSource)
+	atomic.StoreUint32(&b.seq, 0)
+	b.configured = true
+	if b.faults != nil && !DebounceRunning() { // a relay started just after Configure is fine, so allow it a grace period
+		time.AfterFunc(debounceGrace, func() {
+			if !DebounceRunning() {
+				b.fault(ERROR_NO_DEBOUNCE)
+			}
+		})
+	}
+	return nil
+}
+
+// applyConfig applies cfg, on the RecognizeAndPublish goroutine if it's running, as it reads the fields apply writes
+func (b *bouncer) applyConfig(cfg Config) error {
+	b.runMu.Lock()
+	if !b.running {
+		defer b.runMu.Unlock()
+		return b.apply(cfg)
+	}
+	b.runMu.Unlock()
+	req := configRequest{cfg: cfg, err: make(chan error, 1)}
+	select {
+	case b.cfgCh <- req:
+	case <-b.done:
+		return b.newError(ERROR_STOPPED)
+	}
+	return <-req.err
+}
+
+// apply validates cfg and applies everything in it but the pin setup
+func (b *bouncer) apply(cfg Config) error {
+	if cfg.Pull > PullNone {
+		return b.newError(ERROR_INVALID_PULL)
+	}
+	if cfg.Edges > RisingEdge {
+		return b.newError(ERROR_INVALID_EDGES)
+	}
+	if err := b.SetDurations(cfg); err != nil {
 		return err
 	}
-	if b.shortPress > 0 {
-		b.shortPress = cfg.Short
+	b.durMu.Lock()
+	b.cfg = cfg
+	b.activeHigh = cfg.ActiveHigh
+	b.durMu.Unlock()
+	b.pull = cfg.Pull
+	b.doubleGap = cfg.DoubleGap
+	b.multiClickGap = cfg.ClickGap
+	b.debounceInterval = cfg.Debounce
+	b.releaseSettle = cfg.ReleaseSettle
+	if cfg.Latching && cfg.ReleaseSettle == 0 {
+		b.releaseSettle = latchingSettle
+	}
+	b.interPressGap = cfg.InterPressGap
+	b.minPress = cfg.MinPress
+	b.cooldown = cfg.Cooldown
+	b.tickPeriod = cfg.TickPeriod
+	b.notifyStart = cfg.NotifyPressStarted
+	b.repeatDelay = cfg.RepeatDelay
+	b.repeatInterval = cfg.RepeatInterval
+	b.notifyHeld = cfg.NotifyThresholds
+	b.hysteresis = cfg.Hysteresis
+	recent := 0
+	if cfg.Recent > 0 {
+		recent = cfg.Recent
+	}
+	b.statsMu.Lock()
+	if recent != len(b.recent) { // resizing discards the events kept so far
+		b.recent = make([]Event, recent)
+		b.recentNext, b.recentCount = 0, 0
+	}
+	b.statsMu.Unlock()
+	b.publishBounces = cfg.PublishBounces
+	b.integratorMax = cfg.Integrator
+	b.poll = cfg.Poll
+	b.edges = cfg.Edges
+	b.tickSource = cfg.TickSource
+	b.classify = cfg.Classify
+	b.now = time.Now
+	if cfg.Clock != nil {
+		b.now = cfg.Clock.Now
+	}
+	b.exclusiveBounds = cfg.ExclusiveBounds
+	b.sleepGap = cfg.SleepGap
+	b.stuckThreshold = cfg.StuckThreshold
+	b.longOnThreshold = cfg.LongPressOnThreshold || cfg.TapOrHold
+	b.tapOrHold = cfg.TapOrHold
+	b.cumulative = cfg.Cumulative
+	b.latching = cfg.Latching
+	b.armFire = cfg.ArmFire
+	b.faults = cfg.Faults
+	return nil
+}
+
+// SetDurations overrides the Tap, Short, Long & ExtraLong durations given non-zero in cfg, and may be called
+// while RecognizeAndPublish is running; like Configure, it returns an error if the result is misordered.
+// A press is classified against one consistent set of durations, those current as it began: changes take effect
+// from the next press, so a press in progress isn't announced by one set & classified by another
+func (b *bouncer) SetDurations(cfg Config) error {
+	b.durMu.Lock()
+	defer b.durMu.Unlock()
+	tap, short, long, extraLong := b.tap, b.shortPress, b.longPress, b.extraLongPress
+	if cfg.Tap > 0 {
+		tap = cfg.Tap
+	}
+	if cfg.Short > 0 {
+		short = cfg.Short
+	}
+	if cfg.Long > 0 {
+		long = cfg.Long
+	}
+	if cfg.ExtraLong > 0 {
+		extraLong = cfg.ExtraLong
+	}
+	if !validDurations(short, long, extraLong) || (tap > 0 && tap >= short) {
+		return b.newError(ERROR_INVALID_PRESSLENGTH)
 	}
-	if b.longPress > 0 {
-		b.longPress = cfg.Long
+	b.tap, b.shortPress, b.longPress, b.extraLongPress = tap, short, long, extraLong
+	return nil
+}
+
+// WithBands replaces the Tap, Short, Long & ExtraLong bands with any number of custom ones; a released press is
+// published with the Label of the longest band it reached, or as Bounce if it's shorter than them all. Labels
+// are PressLengths of your choosing; pick values past the built-in ones unless you mean e.g. ShortPress to still
+// count towards DoubleClick. Threshold crossings (NotifyThresholds) aren't published while custom bands are in
+// use. Passing no bands reverts to the fixed durations. May be called while RecognizeAndPublish is running; as
+// with SetDurations, the change takes effect from the next press
+func (b *bouncer) WithBands(bands []Band) error {
+	sorted := make([]Band, len(bands))
+	copy(sorted, bands)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Min > sorted[j].Min })
+	for i := range sorted {
+		if sorted[i].Min <= 0 || (i > 0 && sorted[i].Min == sorted[i-1].Min) {
+			return b.newError(ERROR_INVALID_BANDS)
+		}
 	}
-	if b.extraLongPress > 0 {
-		b.extraLongPress = cfg.ExtraLong
+	b.durMu.Lock()
+	defer b.durMu.Unlock()
+	if len(sorted) == 0 {
+		b.bands = nil
+		return nil
 	}
-	addSysTickConsumer(b.tickerCh)
+	b.bands = sorted
 	return nil
 }
 
-// State returns an on-demand measurement of the bouncer's pin
-func (b *bouncer) State() bool {
-	return b.pin.Get()
+// GetConfig returns the configuration in effect, with the defaults in place of any fields left zero
+func (b *bouncer) GetConfig() Config {
+	b.durMu.RLock()
+	cfg := b.cfg
+	b.durMu.RUnlock()
+	cfg.Tap, cfg.Short, cfg.Long, cfg.ExtraLong = b.Duration(Tap), b.Duration(ShortPress), b.Duration(LongPress), b.Duration(ExtraLongPress)
+	cfg.ISRBufferSize = cap(b.isrChan)
+	cfg.TickBufferSize = cap(b.tickerCh)
+	return cfg
+}
+
+// State returns an on-demand measurement of the bouncer's pin as Pressed or Released
+func (b *bouncer) State() ButtonState {
+	if b.isDown(b.pin.Get()) {
+		return Pressed
+	}
+	return Released
+}
+
+// Name returns the name given to NewNamed, or an empty string
+func (b *bouncer) Name() string {
+	return b.name
+}
+
+// ID identifies the bouncer stably, e.g. for a handler shared by several: its name if it has one, otherwise
+// "pin " & its machine.Pin's number, or an empty string for a bouncer made by NewWithPin from another InputPin
+func (b *bouncer) ID() string {
+	switch {
+	case b.name != "":
+		return b.name
+	case b.Pin() != machine.NoPin:
+		return "pin " + strconv.Itoa(int(b.Pin()))
+	}
+	return ""
+}
+
+// Pin returns the machine.Pin the bouncer reads, or machine.NoPin if it was made with NewWithPin from another InputPin
+func (b *bouncer) Pin() machine.Pin {
+	if p, ok := b.pin.(machine.Pin); ok {
+		return p
+	}
+	return machine.NoPin
+}
+
+// Stats returns a snapshot of the bouncer's counters
+func (b *bouncer) Stats() Stats {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	st := b.stats
+	st.Presses = make(map[PressLength]uint32, len(b.stats.Presses))
+	for l, n := range b.stats.Presses {
+		st.Presses[l] = n
+	}
+	return st
+}
+
+// Timing returns a snapshot of the measured durations of the presses classified on release since boot, by the
+// PressLength they were classified as, e.g. to pick Short, Long & ExtraLong thresholds that match how your buttons
+// are actually pressed. Presses already published while held (as Repeat, or by LongPressOnThreshold) aren't included
+func (b *bouncer) Timing() map[PressLength]BandTiming {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	timing := make(map[PressLength]BandTiming, len(b.timing))
+	for l, t := range b.timing {
+		timing[l] = BandTiming{Count: t.count, Min: t.min, Max: t.max, Avg: t.sum / time.Duration(t.count)}
+	}
+	return timing
+}
+
+// recordTiming accumulates the duration of a press classified on release as l
+func (b *bouncer) recordTiming(l PressLength, d time.Duration) {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	if b.timing == nil {
+		b.timing = make(map[PressLength]*bandTiming)
+	}
+	t, ok := b.timing[l]
+	if !ok {
+		b.timing[l] = &bandTiming{count: 1, min: d, max: d, sum: d}
+		return
+	}
+	t.count += 1
+	t.sum += d
+	if d < t.min {
+		t.min = d
+	}
+	if d > t.max {
+		t.max = d
+	}
+}
+
+// Recent returns a snapshot of the latest published Events, oldest first, up to Config.Recent of them
+func (b *bouncer) Recent() []Event {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	events := make([]Event, 0, b.recentCount)
+	for i := 0; i < b.recentCount; i++ {
+		events = append(events, b.recent[(b.recentNext-b.recentCount+i+len(b.recent))%len(b.recent)])
+	}
+	return events
+}
+
+// LastPress returns the latest published event & when it was published, for displays that poll rather than
+// subscribe; the bool is false if nothing has been published yet
+func (b *bouncer) LastPress() (PressLength, time.Time, bool) {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	return b.lastPress, b.lastPressAt, !b.lastPressAt.IsZero()
+}
+
+// HeldFor returns how long the press in progress has been held so far, or zero if there isn't one
+func (b *bouncer) HeldFor() time.Duration {
+	b.seqMu.Lock()
+	defer b.seqMu.Unlock()
+	if b.btnDown.IsZero() {
+		return 0
+	}
+	return b.now().Sub(b.btnDown)
+}
+
+// InProgress returns true while a press is in progress, i.e. the button has gone down & not yet come up
+func (b *bouncer) InProgress() bool {
+	b.seqMu.Lock()
+	defer b.seqMu.Unlock()
+	return !b.btnDown.IsZero()
+}
+
+// Pressed returns true if the button is currently held down
+func (b *bouncer) Pressed() bool {
+	return b.State() == Pressed
 }
 
 // RecognizeAndPublish should be a goroutine; reads pin state & sample time from channel,
 // awaits completion of a buttonDown -> buttonUp sequence, recognizes press length,
-// publishes the recognized press event to the button's output channel(s)
+// publishes the recognized press event to the button's output channel(s).
+// When a ClickGap (or DoubleGap) is configured, ShortPresses are held back & counted until no further click
+// begins within the gap, then published as one event: ShortPress for one click, DoubleClick for two, MultiClick
+// for more. The gap is checked on each systick, so clicks are published up to one tick after it expires; a
+// longer press ends the clicks, which are published before it. With only a DoubleGap, clicks are counted up to two.
+// When a RepeatInterval is configured, Repeat is published on the systick at which the hold passes
+// RepeatDelay and then every RepeatInterval until release; a press that repeated isn't classified on release.
+// When NotifyThresholds is configured, HeldShortPress, HeldLongPress & HeldExtraLongPress are each published
+// on the systick at which a held button crosses their threshold; the press is still classified on release.
+// With a Hysteresis h, a threshold T is only crossed once the hold reaches T+h (per ExclusiveBounds), leaving a
+// deadband [T, T+h) in which a press released is classified by T alone & was never announced as crossing it.
+// A hold ending within h of T - i.e. within the jitter h allows for - then never flickers a crossing event;
+// within a press, crossings only ever go up, one threshold at a time.
+// Should an up edge be missed, a press that has lasted twice ExtraLongPress is checked against the pin
+// on each systick, and if the button is found released, Timeout is published & a new sequence awaited.
+// When an Integrator is configured, pin interrupts aren't used; instead the pin is sampled on each systick,
+// counting up (to Integrator) while it reads 'down' & down (to zero) while it reads 'up'. Only when the count
+// saturates does the button go 'down' (or 'up'), so a signal that chatters for many ticks is still debounced.
+// When Poll is configured, pin interrupts aren't used either; the pin is sampled on each systick & any change
+// in level since the last sample is handled as an edge, so edges are only seen at the systick's resolution.
+// When Edges is FallingEdge or RisingEdge, only that edge interrupts; the other is found by sampling as in Poll
+// mode, so it's seen up to a systick late (lengthening or shortening the measured press by as much).
+// When a SleepGap is configured, a gap of more than SleepGap between systicks is taken to mean the MCU slept
+// (pausing the systick but not the clock); a press that began before the gap can't be timed, so it's discarded
+// rather than published as e.g. a bogus ExtraLongPress. A press beginning after waking is recognized as usual.
+// An Event classified on release carries Release: the time from the press's last 'down' edge - the contact last
+// closing, whether at the end of the press's own bounce or on re-closing after chattering mid-hold - to the up edge
+// that concluded it. That's as near as one contact comes to a velocity: with Duration, it tells a firm press (one
+// settled closure, Release close to Duration) from a hesitant or rolling one (Release much shorter than Duration).
+// TapOrHold is for a button that does one thing when tapped & another when held, where the tap mustn't happen
+// if a hold develops. Classifying on release would do that, but the hold's action would wait for the release,
+// & the user can't tell when they've held long enough. So it implies LongPressOnThreshold: LongPress is published
+// the moment a hold reaches Long, & ShortPress only on a release before then, when no hold can develop; a press
+// publishes one or the other, never both, & no ExtraLongPress. A release exactly at Long is LongPress (published
+// on release, as the systick hadn't yet seen the hold reach Long), or ShortPress with ExclusiveBounds.
+// When ArmFire is configured, a held button reaching the Long duration publishes Armed; on release, an Armed press
+// publishes Fired & any other Aborted (or, if too short to be a press, Bounce as usual) - & nothing else is
+// classified. As a bounce of the contact mid-hold mustn't fire (or abort), an up edge is only believed in this
+// mode if the pin still reads released when it's handled; otherwise it's rejected like an up edge within Debounce.
+// When LongPressOnThreshold is configured, LongPress is published on the systick at which a held button reaches
+// the Long duration, for "hold to confirm" buttons; the press has then been published, so on release it's only
+// published again if it was held on past ExtraLong, as ExtraLongPress.
+// By default a longer press cancels the shorter bands' events: a press is published as the one band it ends in.
+// When Cumulative is configured, it doesn't: a press classified on release as LongPress publishes ShortPress then
+// LongPress (& an ExtraLongPress publishes ShortPress, LongPress then ExtraLongPress), all on release; with
+// LongPressOnThreshold too, LongPress is published at its threshold & ShortPress still on release. Cumulative
+// doesn't apply to custom bands, TapOrHold or ArmFire, whose events are exclusive by design.
+// When a StuckThreshold is configured, a press held that long is taken to be a stuck or shorted button: Stuck is
+// published once, on the systick at which it's reached, & nothing more is published for the press - no Repeats,
+// threshold crossings or classification on release. The next press after its release is recognized as usual.
+// When Latching is configured, the button is a maintained switch rather than a momentary one: SwitchedOn is
+// published once its closure outlasts the debounce interval & SwitchedOff once it opens, with Duration how long it
+// was on, & durations are never classified (so no Repeats, threshold crossings, clicks or Stuck). A closure opening
+// within the debounce interval publishes nothing, & a ReleaseSettle (20ms unless configured) keeps the chatter as
+// it opens from switching it back on; a missed opening is still published as Timeout. A switch already on when
+// RecognizeAndPublish starts publishes nothing until it's next switched; read State for its position meanwhile.
+// Runtime faults which would otherwise pass unnoticed are sent to Config.Faults, if set: interrupts dropped
+// because isrChan was full (ERROR_DROPPED_EDGES, checked each systick), a missed up edge (ERROR_MISSED_UP_EDGE),
+// a press spanning a sleep (ERROR_SLEPT_DURING_PRESS), a stuck button (ERROR_STUCK) & a press whose duration
+// can't be right (ERROR_IMPOSSIBLE_DURATION) - negative, or zero yet debounced, from a stale press start or a
+// Config.Clock stepped back - which is discarded rather than taken for a Bounce. A fault is dropped if the channel
+// can't take it straight away
 func (b *bouncer) RecognizeAndPublish() {
-	ticks := 0                  // ticks will begin to increment when a button 'down' is registered
-	btnDown := time.Time{}      // btnDown is the beginning time of a button press event
-	dur := btnDown.Sub(btnDown) // initial duration zero
+	select {
+	case <-b.done: // we were stopped before being started
+		return
+	default:
+	}
+	b.runMu.Lock()
+	b.running = true
+	b.runMu.Unlock()
+	defer func() {
+		b.runMu.Lock()
+		b.running = false
+		b.runMu.Unlock()
+	}()
+	b.polledLevel = !b.isDown(true) // assume the button starts 'up' until sampled otherwise
 	for {
 		select {
+		case <-b.done:
+			return
 		case <-b.tickerCh:
-			if ticks == 0 { // we aren't listening
-				btnDown = time.Time{} // ensure this is empty because occasionally it isn't
+			if atomic.LoadUint32(&b.paused) == 1 {
+				b.idle()
 				continue
-			} else {
-				ticks += 1
 			}
-		case up := <-b.isrChan:
-			switch up {
-			case true: // button is 'up'
-				if ticks == 0 { // if we were awaiting a new bounce sequence to begin
-					continue // ignore 'up' signal & reset the loop
-				} else { // if we were awaiting the conclusion of a bounce sequence
-					if ticks >= 2 { // if the interval between down & up is greater than systick interval
-						dur = time.Now().Sub(btnDown) // calculate sequence duration
-						ticks = 0                     // stop & reset ticks + look for new bounce sequence
-						btnDown = time.Time{}         // reset button down time
-						// Recognize & publish to channel(s)
-						b.publish(b.recognize(dur))
-					} // or ignore & await next buttonUp if debounce interval was not exceeded
+			b.tick()
+			switch {
+			case b.integratorMax > 0:
+				b.integrate(b.pin.Get())
+			case b.poll || b.edges != BothEdges: // an edge that doesn't interrupt is found here
+				if level := b.pin.Get(); level != b.polledLevel {
+					b.polledLevel = level
+					b.edge(level)
 				}
-			case false: // button is 'down'
-				if ticks == 0 { // if we were awaitng a new bounce sequence to begin
-					ticks = 1            // set ticks to 1 so that ticks begins to increment with each received systick
-					btnDown = time.Now() // set now as the beginning of the sequence
-					continue             // reset the loop
-				} // otherwise if we were awaiting the conclusion of a bounce sequence, ignore
 			}
+		case level := <-b.isrChan:
+			if atomic.LoadUint32(&b.paused) == 1 {
+				b.idle()
+				continue
+			}
+			b.polledLevel = level
+			b.edge(level)
+		case <-b.resetCh:
+			// discards the press in progress & resamples a sampled pin, in case SetPolarity changed its meaning;
+			// interrupts await the next edge
+			b.idle()
+		case req := <-b.cfgCh:
+			req.err <- b.apply(req.cfg)
 		}
 	}
 }
 
-// Duration returns the duration of the passed-in PressLength
-func (b *bouncer) Duration(l PressLength) time.Duration {
-	switch l {
-	case ShortPress:
-		return b.shortPress
-	case LongPress:
-		return b.longPress
-	case ExtraLongPress:
-		return b.extraLongPress
-	default:
-		return 0
+// idle keeps RecognizeAndPublish's state empty while paused, following the pin in Integrator & Poll modes
+// so that resuming doesn't see a stale edge
+func (b *bouncer) idle() {
+	b.endSequence()
+	b.clicks = 0
+	if !b.sampled() && b.edges == BothEdges {
+		return
+	}
+	level := b.pin.Get()
+	b.polledLevel = level
+	b.integratedDown = b.isDown(level)
+	b.integrator = 0
+	if b.integratedDown {
+		b.integrator = b.integratorMax
 	}
 }
 
-// publish concurrently sends a PressLength to all channels subscribed to this Bouncer
-func (b *bouncer) publish(p PressLength) {
-	for i := range b.outChans {
-		select {
-		case b.outChans[i] <- p:
-		default:
+// edge handles a change in the pin's level, as read by the interrupt handler or sampled in Poll mode
+func (b *bouncer) edge(level bool) {
+	if b.isDown(level) {
+		b.buttonDown()
+	} else {
+		b.buttonUp()
+	}
+}
+
+// isDown reports whether the pin reading level means the button is down: low if active-low (the default, with
+// the pin pulled up while the button is up), or high if ActiveHigh. Every reading of the pin is interpreted here
+func (b *bouncer) isDown(level bool) bool {
+	b.durMu.RLock()
+	defer b.durMu.RUnlock()
+	return level == b.activeHigh
+}
+
+// tick handles a systick received by RecognizeAndPublish
+func (b *bouncer) tick() {
+	now := b.now()
+	b.drainOutputs()
+	if b.spannedSleep(now) { // the press in progress can't be timed
+		b.endSequence()
+		b.fault(ERROR_SLEPT_DURING_PRESS)
+	}
+	b.lastTick = now
+	if d := atomic.LoadUint32(&b.droppedEdges); d != b.reportedDrops {
+		b.reportedDrops = d
+		b.fault(ERROR_DROPPED_EDGES)
+	}
+	if b.clicks > 0 && b.ticks == 0 && now.Sub(b.clickAt) > b.clickGap() { // no further click began in time
+		b.publishClicks()
+	}
+	if b.downRejected && b.ticks == 0 && !b.downBlocked(now) {
+		b.downRejected = false
+		if b.State() == Pressed { // a down edge ignored as release bounce (or resettling) was a real press after all
+			b.buttonDown()
+		}
+	}
+	if b.ticks == 0 { // we aren't listening
+		return
+	}
+	if b.ticks < maxTicks {
+		b.ticks += 1
+	}
+	if !b.announced && b.debounced(b.ticks, b.btnDown) {
+		if !b.lastEdgeDown { // a glitch: its up edge came within the debounce interval & the button hasn't gone down since
+			b.endSequence()
+			return
+		}
+		b.announce()
+	}
+	held := now.Sub(b.btnDown)
+	if held > 2*b.th.extraLong && b.State() == Released { // the up edge was missed
+		at := b.btnDown
+		b.endSequence()
+		b.publish(Event{Length: Timeout, Duration: held, At: at})
+		b.fault(ERROR_MISSED_UP_EDGE)
+		return
+	}
+	if b.latching || b.stuck { // nothing more is published until release
+		return
+	}
+	if b.stuckThreshold > 0 && held >= b.stuckThreshold {
+		if b.clicks > 0 { // a held press can't be another click
+			b.publishClicks()
+		}
+		b.stuck = true
+		b.publish(Event{Length: Stuck, Duration: held, At: b.btnDown})
+		b.fault(ERROR_STUCK)
+		return
+	}
+	if b.repeatInterval > 0 && held >= b.repeatDelay+time.Duration(b.repeats)*b.repeatInterval {
+		if b.clicks > 0 { // a held press can't be another click
+			b.publishClicks()
+		}
+		b.repeats += 1
+		b.publish(Event{Length: Repeat, Duration: held, At: b.btnDown})
+	}
+	if b.armFire && !b.armed && b.reached(held, b.th.long) {
+		if b.clicks > 0 { // a held press can't be another click
+			b.publishClicks()
+		}
+		b.armed = true
+		b.publish(Event{Length: Armed, Duration: held, At: b.btnDown})
+	}
+	if b.longOnThreshold && !b.firedLong && b.reached(held, b.th.long) {
+		if b.clicks > 0 { // a held press can't be another click
+			b.publishClicks()
+		}
+		b.firedLong = true
+		b.publish(Event{Length: LongPress, Duration: held, At: b.btnDown})
+	}
+	if b.notifyHeld && len(b.th.bands) == 0 && b.classify == nil {
+		l := b.recognizeBy(b.th, held-b.hysteresis)
+		if l == Tap { // not a threshold with a Held counterpart
+			l = Bounce
+		}
+		for b.crossed < l { // publish each threshold crossed since the last tick
+			b.crossed += 1
+			b.publish(Event{Length: heldLength(b.crossed), Duration: held, At: b.btnDown})
 		}
 	}
 }
 
-// recognize returns a PressLength resulting from a passed-in duration matching a Bouncer's durations
-func (b *bouncer) recognize(d time.Duration) PressLength {
-	if d >= b.extraLongPress { // duration was extraLongPress
-		return ExtraLongPress
-	} else if d < b.extraLongPress && d >= b.longPress { // duration was longPress
-		return LongPress
-	} else if d < b.longPress && d >= b.shortPress { // duration was shortPress
-		return ShortPress
+// buttonDown handles a 'down' edge received by RecognizeAndPublish
+func (b *bouncer) buttonDown() {
+	b.lastDownEdge = b.now()
+	b.lastEdgeDown = true
+	if b.ticks != 0 { // if we were awaiting the conclusion of a bounce sequence, ignore
+		return
+	}
+	if b.downBlocked(b.now()) { // the contact bouncing as it opened, or a finger resettling after a press
+		b.statsMu.Lock()
+		b.stats.Rejected += 1
+		b.statsMu.Unlock()
+		b.downRejected = true
+		return
+	}
+	if b.clicks > 0 && b.now().Sub(b.clickAt) > b.clickGap() { // too late to be another click
+		b.publishClicks()
+	}
+	b.ticks = 1                  // set ticks to 1 so that ticks begins to increment with each received systick
+	b.th = b.currentThresholds() // SetDurations & WithBands take effect from the next press
+	b.setBtnDown(b.now())        // set now as the beginning of the sequence
+	b.press += 1
+	b.phase(Event{Length: PressStarted, At: b.btnDown, Press: b.press})
+	if b.notifyStart && !b.latching {
+		b.publish(Event{Length: PressStarted, At: b.btnDown})
 	}
-	return Bounce // should be unreachable
 }
 
-// addSysTickConsumer appends a channel to the pkg-level SysTickSubscriber slice.
-// each Bouncer is added to this slice in New and ticks are relayed by spawning RelayTicks
-func addSysTickConsumer(ch chan struct{}) {
-	sysTickSubcribers = append(sysTickSubcribers, sysTickSubscriber{channel: ch})
+// downBlocked reports whether a down edge at now is to be ignored, coming within ReleaseSettle of the last release or
+// within InterPressGap of the last press being published
+func (b *bouncer) downBlocked(now time.Time) bool {
+	return (b.releaseSettle > 0 && now.Sub(b.lastRelease) < b.releaseSettle) ||
+		(b.interPressGap > 0 && now.Sub(b.lastConcluded) < b.interPressGap)
 }
 
-// sendTicks sends a signal to each Bouncer in the package-level SysTickSubscribers slice
-func sendTicks() {
-	if len(sysTickSubcribers) > 0 {
-		for _, c := range sysTickSubcribers {
-			c.channel <- struct{}{}
+// buttonUp handles an 'up' edge received by RecognizeAndPublish
+func (b *bouncer) buttonUp() {
+	b.lastEdgeDown = false
+	if b.ticks == 0 { // if we were awaiting a new bounce sequence to begin, ignore
+		return
+	}
+	if b.spannedSleep(b.now()) { // the press in progress can't be timed
+		b.endSequence()
+		b.fault(ERROR_SLEPT_DURING_PRESS)
+		return
+	}
+	if b.now().Before(b.btnDown) { // a stale btnDown, or a Config.Clock stepped back: not a bounce, which is merely short
+		b.endSequence()
+		b.fault(ERROR_IMPOSSIBLE_DURATION)
+		return
+	}
+	if !b.debounced(b.ticks, b.btnDown) { // ignore & await next buttonUp if debounce interval was not exceeded
+		b.statsMu.Lock()
+		b.stats.Rejected += 1
+		b.statsMu.Unlock()
+		if b.publishBounces {
+			now := b.now()
+			b.publish(Event{Length: Bounce, Duration: now.Sub(b.btnDown), At: b.btnDown, End: now})
+		}
+		return
+	}
+	if !b.announced { // released before a systick saw the press outlast the debounce interval
+		b.announce()
+	}
+	if b.armFire && b.State() == Pressed { // the contact has closed again: a bounce mid-hold, not a release
+		b.statsMu.Lock()
+		b.stats.Rejected += 1
+		b.statsMu.Unlock()
+		return
+	}
+	up := b.now()
+	b.lastRelease = up
+	dur := up.Sub(b.btnDown) // calculate sequence duration
+	if dur <= 0 {            // debounced with no time passing: as impossible as a negative duration
+		b.endSequence()
+		b.fault(ERROR_IMPOSSIBLE_DURATION)
+		return
+	}
+	if b.latching {
+		at := b.btnDown
+		b.endSequence()
+		b.publish(Event{Length: SwitchedOff, Duration: dur, At: at, End: up})
+		return
+	}
+	at := b.btnDown // keep the start of the sequence for the Event
+	stuck := b.stuck
+	firedLong := b.firedLong && b.repeats == 0 && !stuck
+	published := b.repeats > 0 || b.firedLong || stuck
+	armed := b.armed
+	b.endSequence()
+	if b.armFire && !stuck {
+		b.armOutcome(armed, Event{Length: b.recognizeBy(b.th, dur), Duration: dur, At: at, End: up, Release: up.Sub(b.lastDownEdge)})
+		return
+	}
+	if published { // this press was already published as Repeats, on reaching LongPress, or as Stuck
+		if firedLong && b.cumulative && !b.tapOrHold { // the shorter band's event still fires on release
+			b.publish(Event{Length: ShortPress, Duration: dur, At: at, End: up, Release: up.Sub(b.lastDownEdge)})
 		}
+		if firedLong && !b.tapOrHold && b.recognizeBy(b.th, dur) == ExtraLongPress { // held on past LongPress
+			b.publish(Event{Length: ExtraLongPress, Duration: dur, At: at, End: up, Release: up.Sub(b.lastDownEdge)})
+		}
+		return
+	}
+	// Recognize & publish to channel(s)
+	e := Event{Length: b.recognizeBy(b.th, dur), Duration: dur, At: at, End: up, Release: up.Sub(b.lastDownEdge)}
+	if dur < b.minPress { // a ghost: the up edge was believed, but the contact was too brief to be a press
+		b.statsMu.Lock()
+		b.stats.Rejected += 1
+		b.statsMu.Unlock()
+		e.Length = Bounce
+	}
+	if b.tapOrHold && e.Length == ExtraLongPress { // a hold, which a late systick didn't see reach Long
+		e.Length = LongPress
+	}
+	b.recordTiming(e.Length, dur)
+	switch {
+	case e.Length == Bounce && !b.publishBounces: // too short to be a press
+	case b.clickGap() == 0: // click counting is disabled
+		b.publishPress(e)
+	case e.Length == ShortPress: // hold this back in case further clicks follow
+		if b.clicks == 0 {
+			b.firstClick = e
+		}
+		b.clicks += 1
+		b.clickAt = up
+		if b.multiClickGap == 0 && b.clicks == 2 { // with only a DoubleGap, there's no waiting for a third
+			b.publishClicks()
+		}
+	default: // a longer press ends the clicks so far
+		if b.clicks > 0 {
+			b.publishClicks()
+		}
+		b.publishPress(e)
 	}
 }
 
-// Debounce relays ticks from the SysTick_Handler to all bouncers;
+// publishPress publishes a press classified on release; if Cumulative, it's preceded by the events of the shorter
+// bands it passed through, e.g. ShortPress then LongPress. Only the fixed bands are cumulative, not custom ones
+func (b *bouncer) publishPress(e Event) {
+	longer := e.Length == LongPress || e.Length == ExtraLongPress
+	if longer && b.cumulative && !b.tapOrHold && len(b.th.bands) == 0 && b.classify == nil {
+		for l := ShortPress; l < e.Length; l++ {
+			shorter := e
+			shorter.Length = l
+			b.publish(shorter)
+		}
+	}
+	b.publish(e)
+}
+
+// armOutcome publishes how a press in ArmFire mode ended: Fired if it was Armed, otherwise Aborted, unless it
+// was too short to be a press at all (e, as classified, is Bounce or shorter than MinPress)
+func (b *bouncer) armOutcome(armed bool, e Event) {
+	switch {
+	case armed:
+		e.Length = Fired
+	case e.Length == Bounce || e.Duration < b.minPress:
+		if !b.publishBounces {
+			return
+		}
+		e.Length = Bounce
+	default:
+		e.Length = Aborted
+	}
+	b.publish(e)
+}
+
+// integrate feeds a pin sample to the integrator, passing on a 'down' or 'up' once it saturates
+func (b *bouncer) integrate(level bool) {
+	if b.isDown(level) {
+		if b.integrator < b.integratorMax {
+			b.integrator += 1
+		}
+	} else if b.integrator > 0 {
+		b.integrator -= 1
+	}
+	switch {
+	case b.integrator == b.integratorMax && !b.integratedDown:
+		b.integratedDown = true
+		b.buttonDown()
+	case b.integrator == 0 && b.integratedDown:
+		b.integratedDown = false
+		b.buttonUp()
+	}
+}
+
+// spannedSleep reports whether the press in progress began before a gap of more than SleepGap in the systicks,
+// ending at now; i.e. whether the MCU slept while the button was held
+func (b *bouncer) spannedSleep(now time.Time) bool {
+	return b.sleepGap > 0 && b.ticks > 0 && !b.lastTick.IsZero() &&
+		now.Sub(b.lastTick) > b.sleepGap && !b.btnDown.After(b.lastTick)
+}
+
+// endSequence resets the state of the press in progress, to look for a new bounce sequence
+func (b *bouncer) endSequence() {
+	pressed := b.ticks != 0
+	at, end := b.btnDown, b.now()
+	b.ticks = 0
+	b.setBtnDown(time.Time{})
+	if b.announced {
+		b.announced = false
+		b.stateChanged(false)
+	}
+	b.outMu.Lock()
+	phased := len(b.phaseChans) > 0
+	b.outMu.Unlock()
+	if pressed && phased { // only classified if there's a phase output, as a Classify function may be costly
+		dur := end.Sub(at)
+		b.phase(Event{Length: b.recognizeBy(b.th, dur), Duration: dur, At: at, End: end, Press: b.press})
+	}
+	b.repeats = 0
+	b.crossed = Bounce
+	b.firedLong = false
+	b.armed = false
+	b.stuck = false
+}
+
+// clickGap returns how long after a click another may begin to be counted with it, or zero if clicks aren't counted
+func (b *bouncer) clickGap() time.Duration {
+	if b.multiClickGap > 0 {
+		return b.multiClickGap
+	}
+	return b.doubleGap
+}
+
+// publishClicks publishes the clicks held back so far as a ShortPress, DoubleClick or MultiClick
+func (b *bouncer) publishClicks() {
+	e := b.firstClick
+	e.Clicks = b.clicks
+	switch {
+	case b.clicks == 2:
+		e.Length = DoubleClick
+	case b.clicks > 2:
+		e.Length = MultiClick
+	}
+	if b.clicks > 1 {
+		e.End = b.clickAt
+		e.Duration = e.End.Sub(e.At) // from the first click going down to the last coming up
+	}
+	b.clicks = 0
+	b.publish(e)
+}
+
+// DebounceInterval returns the configured debounce interval, or zero if debouncing is one systick
+func (b *bouncer) DebounceInterval() time.Duration {
+	return b.debounceInterval
+}
+
+// TickPeriod returns the configured systick period, or zero if the bouncer hasn't been told it
+func (b *bouncer) TickPeriod() time.Duration {
+	return b.tickPeriod
+}
+
+// DroppedEdges returns how many pin interrupts have been dropped because they couldn't be queued;
+// if this climbs on a noisy button, try a larger Config.ISRBufferSize
+func (b *bouncer) DroppedEdges() uint32 {
+	return atomic.LoadUint32(&b.droppedEdges)
+}
+
+// Stop makes RecognizeAndPublish return, detaches the pin interrupt & unsubscribes from the systick relay.
+// State still works after Stop, but the bouncer won't recognize presses again
+func (b *bouncer) Stop() {
+	b.stopOnce.Do(func() {
+		close(b.done)
+		atomic.StoreUint32(&b.seq, 0)
+		b.pin.SetInterrupt(0, nil)
+		removeSysTickConsumer(b.tickerCh)
+	})
+}
+
+// Reset discards the press in progress & any clicks held back awaiting a DoubleClick, without publishing them,
+// so that e.g. a button held across a change of mode doesn't produce a spurious LongPress when it's released.
+// It's carried out by RecognizeAndPublish, on its goroutine, so it takes effect once that next runs
+func (b *bouncer) Reset() {
+	select {
+	case b.resetCh <- struct{}{}:
+	default: // a reset is already pending
+	}
+}
+
+// SetPolarity changes whether the button is active-high while the bouncer is running, e.g. for hot-swappable
+// button boards of different polarities; the pin is reconfigured with the matching pull (unless Pull says
+// otherwise) & the press in progress is Reset, as its edges can't be interpreted across the change
+func (b *bouncer) SetPolarity(activeHigh bool) {
+	b.durMu.Lock()
+	changed := b.activeHigh != activeHigh
+	b.activeHigh = activeHigh
+	b.cfg.ActiveHigh = activeHigh
+	b.durMu.Unlock()
+	if !changed {
+		return
+	}
+	if b.configured {
+		b.pin.Configure(machine.PinConfig{Mode: b.pinModeFor(activeHigh)})
+	}
+	b.Reset()
+}
+
+// Pause stops the bouncer recognizing & publishing presses without tearing it down: RecognizeAndPublish keeps
+// draining its systicks & interrupts, so they don't back up, but discards them & holds no press in progress.
+// The pin interrupt stays attached, so a paused bouncer costs a little more than a stopped one
+func (b *bouncer) Pause() {
+	atomic.StoreUint32(&b.paused, 1)
+}
+
+// Resume undoes Pause; a button held down across Resume isn't recognized until it's released & pressed again
+func (b *bouncer) Resume() {
+	atomic.StoreUint32(&b.paused, 0)
+}
+
+// Duration returns the duration of the passed-in PressLength
+func (b *bouncer) Duration(l PressLength) time.Duration {
+	b.durMu.RLock()
+	defer b.durMu.RUnlock()
+	switch l {
+	case Tap:
+		return b.tap
+	case ShortPress:
+		return b.shortPress
+	case LongPress:
+		return b.longPress
+	case ExtraLongPress:
+		return b.extraLongPress
+	case DoubleClick:
+		return b.clickGap()
+	case MultiClick:
+		return b.multiClickGap
+	case Repeat:
+		return b.repeatInterval
+	case HeldShortPress:
+		return b.shortPress
+	case HeldLongPress:
+		return b.longPress
+	case HeldExtraLongPress:
+		return b.extraLongPress
+	default:
+		return 0
+	}
+}
+
+// AddOutput subscribes another channel to this bouncer's events; it's safe to call while RecognizeAndPublish is running
+func (b *bouncer) AddOutput(ch chan PressLength) error {
+	if ch == nil {
+		return b.newError(ERROR_NIL_OUTPUT_CHANNEL)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	if b.hasOutput(ch) {
+		return b.newError(ERROR_DUPLICATE_OUTPUT)
+	}
+	b.outputs = append(b.outputs, output{ch: ch})
+	b.outputsChanged()
+	return nil
+}
+
+// AddReliableOutput is like AddOutput, but rather than dropping an event the channel can't take straight
+// away, publish blocks for up to timeout waiting for it to; events still undelivered are counted in Stats.
+// Other channels are sent to before it waits, & outputs can be added or removed meanwhile, but recognition of the
+// next press is held up
+func (b *bouncer) AddReliableOutput(ch chan PressLength, timeout time.Duration) error {
+	if ch == nil {
+		return b.newError(ERROR_NIL_OUTPUT_CHANNEL)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	if b.hasOutput(ch) {
+		return b.newError(ERROR_DUPLICATE_OUTPUT)
+	}
+	b.outputs = append(b.outputs, output{ch: ch, timeout: timeout})
+	b.outputsChanged()
+	return nil
+}
+
+// AddPriorityOutput subscribes a channel which publish sends to ahead of the other outputs, blocking until it
+// takes the event. Priority outputs are sent to in the order they were added, then the other outputs in the
+// order they were added; a priority subscriber which stops receiving stalls the bouncer, so use them sparingly.
+// Outputs can still be added & removed meanwhile, as publish doesn't hold the outputs' lock while it blocks, &
+// Stop still stops it, abandoning the send
+func (b *bouncer) AddPriorityOutput(ch chan PressLength) error {
+	if ch == nil {
+		return b.newError(ERROR_NIL_OUTPUT_CHANNEL)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	if b.hasOutput(ch) {
+		return b.newError(ERROR_DUPLICATE_OUTPUT)
+	}
+	i := 0
+	for i < len(b.outputs) && b.outputs[i].priority { // after the priority outputs already added
+		i += 1
+	}
+	b.outputs = append(b.outputs, output{})
+	copy(b.outputs[i+1:], b.outputs[i:])
+	b.outputs[i] = output{ch: ch, priority: true}
+	b.outputsChanged()
+	return nil
+}
+
+// AddFilteredOutput is like AddOutput, but the channel only receives the given PressLengths, e.g. only LongPress
+func (b *bouncer) AddFilteredOutput(ch chan PressLength, lengths ...PressLength) error {
+	if ch == nil {
+		return b.newError(ERROR_NIL_OUTPUT_CHANNEL)
+	}
+	if len(lengths) < 1 {
+		return b.newError(ERROR_INVALID_PRESSLENGTH)
+	}
+	filter := make([]PressLength, len(lengths))
+	copy(filter, lengths)
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	if b.hasOutput(ch) {
+		return b.newError(ERROR_DUPLICATE_OUTPUT)
+	}
+	b.outputs = append(b.outputs, output{ch: ch, filter: filter})
+	b.outputsChanged()
+	return nil
+}
+
+// AddCoalescingOutput subscribes a buffered channel which always receives the latest event: when it's full,
+// its oldest pending event is discarded to make room, rather than the new one being dropped. With a buffer of
+// 1, a slow subscriber sees the most recent PressLength rather than a backlog
+func (b *bouncer) AddCoalescingOutput(ch chan PressLength) error {
+	if ch == nil {
+		return b.newError(ERROR_NIL_OUTPUT_CHANNEL)
+	}
+	if cap(ch) < 1 {
+		return b.newError(ERROR_UNBUFFERED_OUTPUT)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	if b.hasOutput(ch) {
+		return b.newError(ERROR_DUPLICATE_OUTPUT)
+	}
+	b.outputs = append(b.outputs, output{ch: ch, coalesce: true})
+	b.outputsChanged()
+	return nil
+}
+
+// outputsChanged updates single after a change to outputs; callers hold outMu, or own b exclusively
+func (b *bouncer) outputsChanged() {
+	b.single = nil
+	if len(b.outputs) == 1 {
+		o := b.outputs[0]
+		if o.timeout == 0 && !o.priority && len(o.filter) == 0 && !o.coalesce && o.queue == nil {
+			b.single = o.ch
+		}
+	}
+}
+
+// hasOutput reports whether ch is already one of the outputs; callers hold outMu, or own b exclusively
+func (b *bouncer) hasOutput(ch chan PressLength) bool {
+	for i := range b.outputs {
+		if b.outputs[i].ch == ch {
+			return true
+		}
+	}
+	return false
+}
+
+// hasEventChan reports whether ch is already one of the Event channels; callers hold outMu, or own b exclusively
+func (b *bouncer) hasEventChan(ch chan Event) bool {
+	for i := range b.eventChans {
+		if b.eventChans[i] == ch {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSourcedOutput adds a channel receiving each event as a SourcedPress, carrying the bouncer & its ID, so one
+// channel (& handler) can be shared by several bouncers. Like Multiplex, but subscribing each bouncer in turn
+func (b *bouncer) AddSourcedOutput(ch chan SourcedPress) error {
+	if ch == nil {
+		return b.newError(ERROR_NIL_OUTPUT_CHANNEL)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.sourcedChans {
+		if b.sourcedChans[i] == ch {
+			return b.newError(ERROR_DUPLICATE_OUTPUT)
+		}
+	}
+	b.sourcedChans = append(b.sourcedChans, ch)
+	return nil
+}
+
+// RemoveSourcedOutput stops publishing to the given channel
+func (b *bouncer) RemoveSourcedOutput(ch chan SourcedPress) error {
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.sourcedChans {
+		if b.sourcedChans[i] == ch {
+			b.sourcedChans = append(b.sourcedChans[:i], b.sourcedChans[i+1:]...)
+			return nil
+		}
+	}
+	return b.newError(ERROR_OUTPUT_NOT_FOUND)
+}
+
+// AddPhaseOutput adds a channel receiving both phases of every press, e.g. note on & note off for a drum pad: as
+// it begins, an Event with Length PressStarted & At; as it ends, one with End, the held Duration & the Length its
+// duration is classified as (by Classify or the bands, if configured, but before click counting, Cumulative & the
+// like), correlated by Press. The end phase comes whether or not the press itself is published - as a click, after
+// Repeats, or even if it's abandoned - so every begin is paired with an end; while there's a phase output, Classify
+// is called for the end phase as well as for the press itself
+func (b *bouncer) AddPhaseOutput(ch chan Event) error {
+	if ch == nil {
+		return b.newError(ERROR_NIL_OUTPUT_CHANNEL)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.phaseChans {
+		if b.phaseChans[i] == ch {
+			return b.newError(ERROR_DUPLICATE_OUTPUT)
+		}
+	}
+	b.phaseChans = append(b.phaseChans, ch)
+	return nil
+}
+
+// RemovePhaseOutput stops sending phases to the given channel
+func (b *bouncer) RemovePhaseOutput(ch chan Event) error {
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.phaseChans {
+		if b.phaseChans[i] == ch {
+			b.phaseChans = append(b.phaseChans[:i], b.phaseChans[i+1:]...)
+			return nil
+		}
+	}
+	return b.newError(ERROR_OUTPUT_NOT_FOUND)
+}
+
+// phase sends a press's begin or end phase to the AddPhaseOutput channels
+func (b *bouncer) phase(e Event) {
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.phaseChans {
+		select {
+		case b.phaseChans[i] <- e:
+		default:
+		}
+	}
+}
+
+// AddBufferedOutput is like AddOutput, but events the channel can't take straight away queue up, to be sent on
+// later systicks (or ahead of the next event) as it can; only once depth events are queued are further ones
+// dropped & counted in Stats. Somewhere between best-effort AddOutput & blocking AddReliableOutput; depth must be
+// positive
+func (b *bouncer) AddBufferedOutput(ch chan PressLength, depth int) error {
+	if ch == nil {
+		return b.newError(ERROR_NIL_OUTPUT_CHANNEL)
+	}
+	if depth <= 0 {
+		return b.newError(ERROR_INVALID_DEPTH)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	if b.hasOutput(ch) {
+		return b.newError(ERROR_DUPLICATE_OUTPUT)
+	}
+	b.outputs = append(b.outputs, output{ch: ch, queue: &outputQueue{depth: depth}})
+	b.outputsChanged()
+	return nil
+}
+
+// drainOutputs sends what it can of the buffered outputs' queued events
+func (b *bouncer) drainOutputs() {
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.outputs {
+		if b.outputs[i].queue != nil {
+			b.outputs[i].drain()
+		}
+	}
+}
+
+// RemoveOutput stops publishing to the given channel; it's safe to call while RecognizeAndPublish is running,
+// though an event already being published may still be sent to it.
+// Removing the last output is allowed: presses are still recognized, counted & passed to OnPress callbacks,
+// and outputs can be added again later (WaitForPress relies on this)
+func (b *bouncer) RemoveOutput(ch chan PressLength) error {
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.outputs {
+		if b.outputs[i].ch == ch {
+			b.outputs = append(b.outputs[:i], b.outputs[i+1:]...)
+			b.outputsChanged()
+			return nil
+		}
+	}
+	return b.newError(ERROR_OUTPUT_NOT_FOUND)
+}
+
+// WaitForPress blocks until the bouncer's next press & returns its PressLength, for simple sequential flows such
+// as a setup wizard; only lengths that conclude a press count (see concludesPress). It returns an error if
+// there's no press within timeout (zero waits indefinitely) or the bouncer is stopped. A temporary output is
+// subscribed for the wait & removed afterwards, so it's safe to call while RecognizeAndPublish is running
+func (b *bouncer) WaitForPress(timeout time.Duration) (PressLength, error) {
+	ch := make(chan PressLength, 1)
+	if err := b.AddOutput(ch); err != nil {
+		return Bounce, err
+	}
+	defer b.RemoveOutput(ch)
+	var expired <-chan time.Time // nil, so never ready, if waiting indefinitely
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	for {
+		select {
+		case l := <-ch:
+			if !concludesPress(l) {
+				continue
+			}
+			return l, nil
+		case <-expired:
+			return Bounce, b.newError(ERROR_WAIT_TIMEOUT)
+		case <-b.done:
+			return Bounce, b.newError(ERROR_STOPPED)
+		}
+	}
+}
+
+// OnPress registers a callback for this bouncer's events, returning an id for RemoveOnPress.
+// Callbacks run on the RecognizeAndPublish goroutine, so they must return quickly
+func (b *bouncer) OnPress(fn func(PressLength)) (int, error) {
+	if fn == nil {
+		return 0, b.newError(ERROR_NIL_CALLBACK)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	b.nextCallbackID += 1
+	b.callbacks = append(b.callbacks, pressCallback{id: b.nextCallbackID, fn: fn})
+	return b.nextCallbackID, nil
+}
+
+// RemoveOnPress unregisters the callback with the given id
+func (b *bouncer) RemoveOnPress(id int) error {
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.callbacks {
+		if b.callbacks[i].id == id {
+			b.callbacks = append(b.callbacks[:i], b.callbacks[i+1:]...)
+			return nil
+		}
+	}
+	return b.newError(ERROR_CALLBACK_NOT_FOUND)
+}
+
+// OnStateChange registers a callback for the bouncer's debounced state, returning an id for RemoveOnStateChange.
+// It's called with true once a press outlasts the debounce interval & false as it ends (or is abandoned), before
+// the press is classified; bounces within a press, & glitches too brief to be one, don't call it. Callbacks run
+// on the RecognizeAndPublish goroutine, so must return quickly
+func (b *bouncer) OnStateChange(fn func(pressed bool)) (int, error) {
+	if fn == nil {
+		return 0, b.newError(ERROR_NIL_CALLBACK)
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	b.nextCallbackID += 1
+	b.stateCallbacks = append(b.stateCallbacks, stateCallback{id: b.nextCallbackID, fn: fn})
+	return b.nextCallbackID, nil
+}
+
+// RemoveOnStateChange unregisters the callback with the given id
+func (b *bouncer) RemoveOnStateChange(id int) error {
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	for i := range b.stateCallbacks {
+		if b.stateCallbacks[i].id == id {
+			b.stateCallbacks = append(b.stateCallbacks[:i], b.stateCallbacks[i+1:]...)
+			return nil
+		}
+	}
+	return b.newError(ERROR_CALLBACK_NOT_FOUND)
+}
+
+// announce reports the press in progress as debounced, publishing SwitchedOn if Latching
+func (b *bouncer) announce() {
+	b.announced = true
+	b.stateChanged(true)
+	if b.latching {
+		b.publish(Event{Length: SwitchedOn, At: b.btnDown})
+	}
+}
+
+// stateChanged calls the OnStateChange callbacks
+func (b *bouncer) stateChanged(pressed bool) {
+	b.outMu.Lock()
+	callbacks := make([]stateCallback, len(b.stateCallbacks))
+	copy(callbacks, b.stateCallbacks)
+	b.outMu.Unlock()
+	for i := range callbacks { // called without the lock so callbacks may add or remove others
+		callbacks[i].fn(pressed)
+	}
+}
+
+// publish sends an Event to all Event channels, and its PressLength to all other channels subscribed to this
+// Bouncer. Sends are made in turn on the RecognizeAndPublish goroutine, not concurrently, so every channel
+// receives events in the order they were published: first to the priority outputs, then to every other channel
+// that can take the event straight away, and only then waiting on any reliable outputs that couldn't, so that a
+// slow reliable subscriber doesn't delay the others
+func (b *bouncer) publish(e Event) {
+	if b.coolingDown(e.Length) {
+		return
+	}
+	e.Seq = atomic.AddUint32(&b.seq, 1)
+	if concludesPress(e.Length) {
+		b.lastConcluded = b.now()
+	}
+	if e.Press == 0 {
+		e.Press = b.press
+	}
+	dropped := uint32(0)
+	// snapshot the outputs, so that sends which block (to a priority output, or awaiting a reliable one) don't hold
+	// outMu, which subscribers adding or removing outputs would otherwise wait on for as long
+	b.outMu.Lock()
+	single := b.single
+	b.snapOutputs = b.snapOutputs[:0]
+	if single == nil {
+		b.snapOutputs = append(b.snapOutputs, b.outputs...)
+	}
+	b.snapEventChans = append(b.snapEventChans[:0], b.eventChans...)
+	b.snapSourcedChans = append(b.snapSourcedChans[:0], b.sourcedChans...)
+	mapped := b.mappedOuts // only ever set by NewMapped, so needn't be copied
+	callbacks := make([]pressCallback, len(b.callbacks))
+	copy(callbacks, b.callbacks)
+	b.outMu.Unlock()
+	if single != nil { // the common case of one plain output: just try to send, skipping the loop
+		select {
+		case single <- e.Length:
+		default:
+		}
+	}
+	var waiting []output // reliable outputs which couldn't take the event straight away
+	for _, o := range b.snapOutputs {
+		if !o.wants(e.Length) {
+			continue
+		}
+		if o.send(e.Length, b.done) {
+			continue
+		}
+		switch {
+		case o.timeout > 0:
+			waiting = append(waiting, o)
+		case o.queue != nil: // its buffer is full
+			dropped += 1
+		}
+	}
+	for _, ch := range b.snapEventChans {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	if len(b.snapSourcedChans) > 0 {
+		sp := SourcedPress{ID: b.ID(), Source: b, Length: e.Length}
+		for _, ch := range b.snapSourcedChans {
+			select {
+			case ch <- sp:
+			default:
+			}
+		}
+	}
+	for i := range waiting {
+		if !waiting[i].await(e.Length) {
+			dropped += 1
+		}
+	}
+	for i := range mapped { // mapped without the lock, as mapFn is the user's
+		mapped[i](e)
+	}
+	b.statsMu.Lock()
+	if b.stats.Presses == nil {
+		b.stats.Presses = make(map[PressLength]uint32)
+	}
+	b.stats.Presses[e.Length] += 1
+	b.stats.Dropped += dropped
+	b.lastPress, b.lastPressAt = e.Length, b.now()
+	if len(b.recent) > 0 {
+		b.recent[b.recentNext] = e
+		b.recentNext = (b.recentNext + 1) % len(b.recent)
+		if b.recentCount < len(b.recent) {
+			b.recentCount += 1
+		}
+	}
+	b.statsMu.Unlock()
+	for i := range callbacks { // called without the lock so callbacks may add or remove outputs
+		callbacks[i].fn(e.Length)
+	}
+}
+
+// coolingDown reports whether an event of length l comes within the Cooldown of the last, & so is suppressed;
+// Repeats are exempt, as their cadence is configured deliberately
+func (b *bouncer) coolingDown(l PressLength) bool {
+	if b.cooldown <= 0 || l == Repeat {
+		return false
+	}
+	now := b.now()
+	if b.lastPublished == nil {
+		b.lastPublished = make(map[PressLength]time.Time)
+	}
+	if last, ok := b.lastPublished[l]; ok && now.Sub(last) < b.cooldown {
+		return true
+	}
+	b.lastPublished[l] = now
+	return false
+}
+
+// sampled reports whether the pin is sampled on each systick, rather than interrupting on each edge
+func (b *bouncer) sampled() bool {
+	return b.integratorMax > 0 || b.poll
+}
+
+// pinChange returns the edges on which the bouncer's pin interrupts
+func (b *bouncer) pinChange() machine.PinChange {
+	switch b.edges {
+	case FallingEdge:
+		return machine.PinFalling
+	case RisingEdge:
+		return machine.PinRising
+	}
+	return machine.PinFalling | machine.PinRising
+}
+
+// pinMode returns the mode the bouncer's pin is configured with
+func (b *bouncer) pinMode() machine.PinMode {
+	return b.pinModeFor(b.activeHigh)
+}
+
+// pinModeFor returns the mode a pin wired with the given polarity is configured with, per the bouncer's Pull
+func (b *bouncer) pinModeFor(activeHigh bool) machine.PinMode {
+	switch b.pull {
+	case PullUp:
+		return machine.PinInputPullup
+	case PullDown:
+		return machine.PinInputPulldown
+	case PullNone:
+		return machine.PinInput
+	}
+	if activeHigh {
+		return machine.PinInputPulldown
+	}
+	return machine.PinInputPullup
+}
+
+// fault reports a runtime fault to Config.Faults, if set, dropping it if the channel can't take it straight away
+func (b *bouncer) fault(msg string) {
+	if b.faults == nil {
+		return
+	}
+	select {
+	case b.faults <- b.newError(msg):
+	default:
+	}
+}
+
+// newError returns an error with the given message, prefixed with the bouncer's name if it has one
+func (b *bouncer) newError(msg string) error {
+	if b.name != "" {
+		return errors.New(b.name + ": " + msg)
+	}
+	return errors.New(msg)
+}
+
+// validDurations reports whether press durations are ordered 0 < short <= long <= extraLong
+func validDurations(short, long, extraLong time.Duration) bool {
+	return short > 0 && short <= long && long <= extraLong
+}
+
+// setBtnDown records the beginning time of the current press, or zero between presses
+func (b *bouncer) setBtnDown(t time.Time) {
+	b.seqMu.Lock()
+	defer b.seqMu.Unlock()
+	b.btnDown = t
+}
+
+// debounced reports whether a sequence begun at btnDown, which has seen the given ticks, has outlasted
+// the debounce interval; without a configured interval, that's one TickPeriod, or failing that, at least one
+// full systick (ticks >= 2) – anywhere between one & two periods of a systick of unknown rate
+func (b *bouncer) debounced(ticks int, btnDown time.Time) bool {
+	if b.debounceInterval > 0 {
+		return b.now().Sub(btnDown) >= b.debounceInterval
+	}
+	if b.tickPeriod > 0 {
+		return b.now().Sub(btnDown) >= b.tickPeriod
+	}
+	return ticks >= 2
+}
+
+// wants reports whether the output's filter (if any) lets l through
+func (o output) wants(l PressLength) bool {
+	if len(o.filter) == 0 {
+		return true
+	}
+	for i := range o.filter {
+		if o.filter[i] == l {
+			return true
+		}
+	}
+	return false
+}
+
+// send delivers l to the output's channel if it can take it straight away (or, for a priority output, once it
+// can, unless done is closed first); it returns false if l wasn't delivered, in which case a reliable output may
+// still await it
+func (o output) send(l PressLength, done chan struct{}) bool {
+	if o.priority {
+		select {
+		case o.ch <- l:
+			return true
+		case <-done: // stopped, so the subscriber may well have gone away
+			return false
+		}
+	}
+	if o.queue != nil {
+		o.drain()
+		if len(o.queue.events) > 0 { // l must wait its turn
+			if len(o.queue.events) >= o.queue.depth {
+				return false
+			}
+			o.queue.events = append(o.queue.events, l)
+			return true
+		}
+	}
+	select {
+	case o.ch <- l:
+		return true
+	default:
+	}
+	if o.queue != nil && o.queue.depth > 0 {
+		o.queue.events = append(o.queue.events, l)
+		return true
+	}
+	if o.coalesce {
+		for { // the subscriber may be receiving concurrently, so retry until l is in
+			select {
+			case <-o.ch: // discard the oldest
+			default:
+			}
+			select {
+			case o.ch <- l:
+				return true
+			default:
+			}
+		}
+	}
+	return false
+}
+
+// drain sends a buffered output's queued events, oldest first, for as long as its channel takes them straight away
+func (o output) drain() {
+	for len(o.queue.events) > 0 {
+		select {
+		case o.ch <- o.queue.events[0]:
+			o.queue.events = o.queue.events[1:]
+		default:
+			return
+		}
+	}
+}
+
+// await blocks for up to the output's timeout for its channel to take l, returning false if it didn't
+func (o output) await(l PressLength) bool {
+	if o.timeout <= 0 {
+		return false
+	}
+	t := time.NewTimer(o.timeout)
+	defer t.Stop()
+	select {
+	case o.ch <- l:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// recognize returns a PressLength resulting from a passed-in duration matching a Bouncer's durations, or its bands,
+// unless a Classify function was configured to do so instead.
+// Each band includes its lower threshold & excludes its upper, so a press of exactly the Long duration is a
+// LongPress; with ExclusiveBounds, each band excludes its lower threshold & includes its upper instead.
+// From the bottom, a press's duration d falls in one of: d < debounce interval, where the up edge isn't believed
+// & recognize isn't reached; debounce interval <= d < Tap (or Short), which is Bounce, dropped by buttonUp unless
+// PublishBounces; then Tap, ShortPress, LongPress & ExtraLongPress
+func (b *bouncer) recognize(d time.Duration) PressLength {
+	return b.recognizeBy(b.currentThresholds(), d)
+}
+
+// recognizeBy is recognize by the given thresholds, e.g. those of the press in progress, rather than the current ones
+func (b *bouncer) recognizeBy(th thresholds, d time.Duration) PressLength {
+	if b.classify != nil {
+		return b.classify(d)
+	}
+	if len(th.bands) > 0 {
+		for i := range th.bands { // longest first
+			if b.reached(d, th.bands[i].Min) {
+				return PressLength(th.bands[i].Label)
+			}
+		}
+		return Bounce
+	}
+	if b.reached(d, th.extraLong) { // duration was extraLongPress
+		return ExtraLongPress
+	} else if b.reached(d, th.long) { // duration was longPress
+		return LongPress
+	} else if b.reached(d, th.short) { // duration was shortPress
+		return ShortPress
+	} else if th.tap > 0 && b.reached(d, th.tap) { // duration was tap
+		return Tap
+	}
+	return Bounce // debounced, but shorter than the shortest band
+}
+
+// currentThresholds returns the durations & bands currently set
+func (b *bouncer) currentThresholds() thresholds {
+	b.durMu.RLock()
+	defer b.durMu.RUnlock()
+	return thresholds{tap: b.tap, short: b.shortPress, long: b.longPress, extraLong: b.extraLongPress, bands: b.bands}
+}
+
+// reached reports whether a duration falls in the band beginning at threshold, per ExclusiveBounds
+func (b *bouncer) reached(d, threshold time.Duration) bool {
+	if b.exclusiveBounds {
+		return d > threshold
+	}
+	return d >= threshold
+}
+
+// heldLength returns the threshold-crossing counterpart of a PressLength
+func heldLength(l PressLength) PressLength {
+	switch l {
+	case ShortPress:
+		return HeldShortPress
+	case LongPress:
+		return HeldLongPress
+	case ExtraLongPress:
+		return HeldExtraLongPress
+	default:
+		return Bounce
+	}
+}
+
+// addSysTickConsumer appends a channel to the pkg-level SysTickSubscriber slice for the given tick source,
+// or moves it to that source if it's already there.
+// each Bouncer is added to this slice in Configure and ticks are relayed by spawning Debounce
+func addSysTickConsumer(ch chan struct{}, source string) {
+	sysTickMu.Lock()
+	defer sysTickMu.Unlock()
+	for i := range sysTickSubcribers {
+		if sysTickSubcribers[i].channel == ch {
+			sysTickSubcribers[i].source = source
+			return
+		}
+	}
+	sysTickSubcribers = append(sysTickSubcribers, sysTickSubscriber{channel: ch, source: source})
+}
+
+// removeSysTickConsumer removes a channel from the pkg-level SysTickSubscriber slice
+func removeSysTickConsumer(ch chan struct{}) {
+	sysTickMu.Lock()
+	defer sysTickMu.Unlock()
+	for i := range sysTickSubcribers {
+		if sysTickSubcribers[i].channel == ch {
+			sysTickSubcribers = append(sysTickSubcribers[:i], sysTickSubcribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// ResetSubscribers unsubscribes everything from the systick relay, e.g. to isolate one test from the next.
+// In production code, prefer stopping bouncers individually with Stop
+func ResetSubscribers() {
+	sysTickMu.Lock()
+	defer sysTickMu.Unlock()
+	sysTickSubcribers = nil
+}
+
+// sendTicks sends a signal to each Bouncer of the given tick source in the package-level SysTickSubscribers slice;
+// a bouncer that hasn't consumed its previous tick misses this one rather than stalling the relay
+func sendTicks(source string) {
+	sysTickMu.RLock()
+	defer sysTickMu.RUnlock()
+	for _, c := range sysTickSubcribers {
+		if c.source != source {
+			continue
+		}
+		select {
+		case c.channel <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Debounce relays ticks from the SysTick_Handler to all bouncers;
 // and is intended to be called as a long-lived goroutine, and only once regarldess of how many bouncers you make.
 // The param tickCh is intended to be the same channel spammed by your SysTick_Handler
 func Debounce(tickCh chan struct{}) {
+	DebounceContext(context.Background(), tickCh)
+}
+
+// DebounceContext is like Debounce, but returns once ctx is done, so the relay can be shut down
+func DebounceContext(ctx context.Context, tickCh chan struct{}) {
+	relay(ctx, "", tickCh)
+}
+
+// DebounceSources is like DebounceContext, but relays several tick sources, e.g. a fast timer & a slow one,
+// keyed by tag; each source's ticks only go to the bouncers configured with that Config.TickSource. The tag ""
+// is the source of bouncers that don't set one (and of Chords & Encoders). One goroutine is started per source
+// beyond the first, which is relayed on the calling goroutine
+func DebounceSources(ctx context.Context, sources map[string]chan struct{}) {
+	var last string
+	for tag := range sources {
+		last = tag
+	}
+	for tag, tickCh := range sources {
+		if tag != last {
+			go relay(ctx, tag, tickCh)
+		}
+	}
+	if len(sources) > 0 {
+		relay(ctx, last, sources[last])
+	}
+}
+
+// DebounceRunning reports whether Debounce (or DebounceContext, or DebounceSources) is running; without it, no
+// systicks are relayed & every bouncer silently fails to recognize presses
+func DebounceRunning() bool {
+	return atomic.LoadInt32(&relaysRunning) > 0
+}
+
+// relay sends each tick received on tickCh to the subscribers of the given tick source, until ctx is done
+func relay(ctx context.Context, source string, tickCh chan struct{}) {
+	atomic.AddInt32(&relaysRunning, 1)
+	defer atomic.AddInt32(&relaysRunning, -1)
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-tickCh:
-			sendTicks()
+			sendTicks(source)
 		}
 	}
 }
@@ -0,0 +1,1480 @@
+package bouncer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"machine"
+
+	"github.com/eyelight/bouncer/bouncertest"
+)
+
+// fakeClock is a Config.Clock stepped by the test
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Unix(1000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// harness drives a bouncer synchronously, as RecognizeAndPublish would, with a fake clock & a systick of period
+// tick: the test sets the pin & steps the clock, so no goroutines are involved
+type harness struct {
+	t      *testing.T
+	b      *bouncer
+	pin    *replayPin
+	clock  *fakeClock
+	period time.Duration
+	events chan Event
+	faults chan error
+}
+
+// newHarness returns a harness for an active-low (unless cfg says otherwise) bouncer configured with cfg,
+// ticking every millisecond
+func newHarness(t *testing.T, cfg Config) *harness {
+	t.Helper()
+	h := &harness{
+		t:      t,
+		pin:    &replayPin{level: !cfg.ActiveHigh},
+		clock:  newFakeClock(),
+		period: time.Millisecond,
+		events: make(chan Event, 256),
+		faults: make(chan error, 16),
+	}
+	h.b = newBouncer(h.pin)
+	h.b.eventChans = append(h.b.eventChans, h.events)
+	cfg.Clock = h.clock
+	if cfg.Faults == nil {
+		cfg.Faults = h.faults
+	}
+	if err := h.b.apply(cfg); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	h.b.polledLevel = h.pin.level
+	return h
+}
+
+// set changes the pin's level, handling it as an interrupt would unless the bouncer samples the pin
+func (h *harness) set(level bool) {
+	h.pin.level = level
+	if !h.b.sampled() && h.b.edges == BothEdges {
+		h.b.polledLevel = level
+		h.b.edge(level)
+	}
+}
+
+// down & up press & release the button, whatever its polarity
+func (h *harness) down() {
+	h.set(h.b.activeHigh)
+}
+
+func (h *harness) up() {
+	h.set(!h.b.activeHigh)
+}
+
+// wait advances the clock by d, a systick at a time, handling each systick as RecognizeAndPublish would
+func (h *harness) wait(d time.Duration) {
+	for end := h.clock.t.Add(d); h.clock.t.Before(end); {
+		h.clock.advance(h.period)
+		h.b.tick()
+		switch {
+		case h.b.integratorMax > 0:
+			h.b.integrate(h.pin.Get())
+		case h.b.poll || h.b.edges != BothEdges:
+			if level := h.pin.Get(); level != h.b.polledLevel {
+				h.b.polledLevel = level
+				h.b.edge(level)
+			}
+		}
+	}
+}
+
+// press holds the button down for d, then releases it
+func (h *harness) press(d time.Duration) {
+	h.down()
+	h.wait(d)
+	h.up()
+}
+
+// setPolarity flips the button's polarity, handling the resulting Reset as RecognizeAndPublish would
+func (h *harness) setPolarity(activeHigh bool) {
+	h.b.SetPolarity(activeHigh)
+	select {
+	case <-h.b.resetCh:
+		h.b.idle()
+	default:
+	}
+}
+
+// published returns the events published so far, & forgets them
+func (h *harness) published() []Event {
+	var got []Event
+	for {
+		select {
+		case e := <-h.events:
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+// lengths returns the PressLengths published so far, & forgets them
+func (h *harness) lengths() []PressLength {
+	var got []PressLength
+	for _, e := range h.published() {
+		got = append(got, e.Length)
+	}
+	return got
+}
+
+// expect fails the test unless exactly want has been published since last checked
+func (h *harness) expect(want ...PressLength) {
+	h.t.Helper()
+	if got := h.lengths(); !equalLengths(got, want) {
+		h.t.Errorf("published %v, want %v", got, want)
+	}
+}
+
+// faulted returns the faults reported so far, & forgets them
+func (h *harness) faulted() []error {
+	var got []error
+	for {
+		select {
+		case err := <-h.faults:
+			got = append(got, err)
+		default:
+			return got
+		}
+	}
+}
+
+// subscribers returns how many channels are subscribed to the systick relay
+func subscribers() int {
+	sysTickMu.RLock()
+	defer sysTickMu.RUnlock()
+	return len(sysTickSubcribers)
+}
+
+// relayTicks runs a systick relay fed as fast as it'll take ticks, until the test is done
+func relayTicks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ticks := make(chan struct{})
+	go DebounceContext(ctx, ticks)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ticks <- struct{}{}:
+			}
+		}
+	}()
+}
+
+// tickEvery runs a systick relay fed every period, as a SysTick_Handler would, until the test is done
+func tickEvery(t *testing.T, period time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ticks := make(chan struct{}, 1)
+	go DebounceContext(ctx, ticks)
+	go func() {
+		tk := time.NewTicker(period)
+		defer tk.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tk.C:
+				select {
+				case ticks <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+}
+
+// expectPress fails the test unless want is received on out within a second
+func expectPress(t *testing.T, out chan PressLength, want PressLength) {
+	t.Helper()
+	select {
+	case l := <-out:
+		if l != want {
+			t.Errorf("published %v, want %v", l, want)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("nothing published, want %v", want)
+	}
+}
+
+func equalLengths(a, b []PressLength) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// newFake returns a bouncer on a FakePin idling high, with one buffered output, & unsubscribes it from the
+// systick relay once the test is done
+func newFake(t *testing.T) (*bouncer, *bouncertest.FakePin, chan PressLength) {
+	t.Helper()
+	pin := bouncertest.NewFakePin(true)
+	out := make(chan PressLength, 16)
+	b, err := NewWithPin(pin, out)
+	if err != nil {
+		t.Fatalf("NewWithPin: %v", err)
+	}
+	t.Cleanup(ResetSubscribers)
+	return b.(*bouncer), pin, out
+}
+
+func TestConfigurePartialKeepsDefaults(t *testing.T) {
+	b, _, _ := newFake(t)
+	if err := b.Configure(Config{Long: 600 * time.Millisecond}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	for l, want := range map[PressLength]time.Duration{
+		ShortPress:     22 * time.Millisecond,
+		LongPress:      600 * time.Millisecond,
+		ExtraLongPress: 1971 * time.Millisecond,
+	} {
+		if got := b.Duration(l); got != want {
+			t.Errorf("Duration(%v) = %v, want %v", l, got, want)
+		}
+	}
+}
+
+// expectFault fails the test unless exactly the fault msg has been reported since last checked
+func (h *harness) expectFault(msg string) {
+	h.t.Helper()
+	got := h.faulted()
+	if len(got) != 1 || got[0].Error() != msg {
+		h.t.Errorf("faults %v, want [%s]", got, msg)
+	}
+}
+
+func TestImpossibleDurationStaleBtnDown(t *testing.T) {
+	h := newHarness(t, Config{Debounce: 5 * time.Millisecond, PublishBounces: true})
+	h.down()
+	h.b.setBtnDown(h.clock.Now().Add(time.Second)) // stale, as if left over from a clock that has since moved
+	h.wait(10 * time.Millisecond)
+	h.up()
+	h.expect()
+	h.expectFault(ERROR_IMPOSSIBLE_DURATION)
+	if h.b.InProgress() {
+		t.Error("press still in progress after an impossible duration")
+	}
+	h.press(100 * time.Millisecond) // the next press is recognized as usual
+	h.expect(ShortPress)
+}
+
+func TestImpossibleDurationClockSteppedBack(t *testing.T) {
+	h := newHarness(t, Config{Debounce: 5 * time.Millisecond, PublishBounces: true})
+	h.down()
+	h.wait(100 * time.Millisecond)
+	h.clock.advance(-150 * time.Millisecond)
+	h.up()
+	h.expect()
+	h.expectFault(ERROR_IMPOSSIBLE_DURATION)
+}
+
+func TestStateChangeAfterDebounce(t *testing.T) {
+	h := newHarness(t, Config{Debounce: 5 * time.Millisecond})
+	var got []bool
+	if _, err := h.b.OnStateChange(func(pressed bool) { got = append(got, pressed) }); err != nil {
+		t.Fatalf("OnStateChange: %v", err)
+	}
+	h.press(2 * time.Millisecond) // a glitch, released within the debounce interval
+	h.wait(20 * time.Millisecond)
+	if len(got) != 0 {
+		t.Errorf("a glitch changed the state: %v", got)
+	}
+	if h.b.InProgress() {
+		t.Error("a glitch is still in progress")
+	}
+	h.expect()
+	h.press(100 * time.Millisecond)
+	if len(got) != 2 || !got[0] || got[1] {
+		t.Errorf("a press changed the state %v, want [true false]", got)
+	}
+	h.expect(ShortPress)
+}
+
+func TestLatchingBounceAsSwitchOpens(t *testing.T) {
+	h := newHarness(t, Config{Latching: true})
+	h.down()
+	h.wait(500 * time.Millisecond)
+	h.up()
+	h.wait(time.Millisecond)
+	h.down() // the contact chatters as the switch opens, for longer than a systick
+	h.wait(2 * time.Millisecond)
+	h.up()
+	h.wait(100 * time.Millisecond)
+	h.expect(SwitchedOn, SwitchedOff)
+	h.down() // a closure opening again within the debounce interval
+	h.up()
+	h.wait(100 * time.Millisecond)
+	h.expect()
+	h.press(100 * time.Millisecond)
+	h.expect(SwitchedOn, SwitchedOff)
+}
+
+func TestPriorityOutputBlocksWithoutLock(t *testing.T) {
+	h := newHarness(t, Config{})
+	prio := make(chan PressLength) // never received from until the outputs have been changed
+	if err := h.b.AddPriorityOutput(prio); err != nil {
+		t.Fatalf("AddPriorityOutput: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		h.b.publish(Event{Length: ShortPress})
+		close(done)
+	}()
+	changed := make(chan struct{})
+	go func() {
+		out := make(chan PressLength, 1)
+		h.b.AddOutput(out)
+		h.b.RemoveOutput(out)
+		close(changed)
+	}()
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("adding & removing an output waited on a blocked priority send")
+	}
+	if l := <-prio; l != ShortPress {
+		t.Errorf("priority output received %v, want ShortPress", l)
+	}
+	<-done
+}
+
+func TestReliableOutputAwaitsWithoutLock(t *testing.T) {
+	h := newHarness(t, Config{})
+	slow := make(chan PressLength) // never received from, so publish waits out the timeout
+	if err := h.b.AddReliableOutput(slow, 500*time.Millisecond); err != nil {
+		t.Fatalf("AddReliableOutput: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		h.b.publish(Event{Length: ShortPress})
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond) // let publish begin waiting
+	start := time.Now()
+	if err := h.b.RemoveOutput(slow); err != nil {
+		t.Fatalf("RemoveOutput: %v", err)
+	}
+	if waited := time.Since(start); waited > 100*time.Millisecond {
+		t.Errorf("RemoveOutput waited %v on a reliable output's timeout", waited)
+	}
+	<-done
+	if got := h.b.Stats().Dropped; got != 1 {
+		t.Errorf("Dropped = %d, want 1", got)
+	}
+}
+
+func TestConfigureRollsBackFailedInterrupt(t *testing.T) {
+	b, pin, _ := newFake(t)
+	if err := b.Configure(Config{}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	pin.FailInterrupt(errors.New("no interrupt on this pin"))
+	if err := b.Configure(Config{Long: 600 * time.Millisecond, ActiveHigh: true}); err == nil {
+		t.Fatal("Configure succeeded despite SetInterrupt failing")
+	}
+	if got := b.Duration(LongPress); got != 500*time.Millisecond {
+		t.Errorf("Duration(LongPress) = %v after rollback, want 500ms", got)
+	}
+	if got := b.GetConfig().ActiveHigh; got {
+		t.Error("ActiveHigh left set after rollback")
+	}
+	if got := pin.Mode(); got != machine.PinInputPullup {
+		t.Errorf("pin mode %v after rollback, want InputPullup", got)
+	}
+	pin.Press()
+	if len(b.isrChan) != 0 {
+		t.Error("interrupt still set after rollback")
+	}
+	if subscribed := subscribers(); subscribed != 0 {
+		t.Errorf("%d systick subscribers after rollback, want 0", subscribed)
+	}
+	pin.Release()
+	pin.FailInterrupt(nil) // the same Configure can simply be retried
+	if err := b.Configure(Config{Long: 600 * time.Millisecond}); err != nil {
+		t.Fatalf("Configure retried: %v", err)
+	}
+	pin.Press()
+	if len(b.isrChan) != 1 {
+		t.Error("interrupt not set by the retried Configure")
+	}
+}
+
+func TestSetPolarityMidIdle(t *testing.T) {
+	for name, cfg := range map[string]Config{"interrupt": {}, "poll": {Poll: true}} {
+		t.Run(name, func(t *testing.T) {
+			h := newHarness(t, cfg)
+			h.wait(10 * time.Millisecond)
+			h.setPolarity(true) // the idle pin, still high, now reads as down
+			h.wait(100 * time.Millisecond)
+			h.up()
+			h.wait(100 * time.Millisecond)
+			h.expect() // not a phantom press
+			h.press(100 * time.Millisecond)
+			h.wait(10 * time.Millisecond)
+			h.expect(ShortPress)
+		})
+	}
+}
+
+func TestSetPolarityMidPress(t *testing.T) {
+	h := newHarness(t, Config{})
+	h.down()
+	h.wait(100 * time.Millisecond)
+	h.setPolarity(true)
+	if h.b.InProgress() {
+		t.Error("press still in progress after SetPolarity")
+	}
+	h.wait(600 * time.Millisecond) // the discarded press publishes nothing, however long it goes on
+	h.expect()
+	h.press(100 * time.Millisecond)
+	h.expect(ShortPress)
+}
+
+func TestZeroOutputs(t *testing.T) {
+	bb, err := NewWithPin(bouncertest.NewFakePin(true))
+	if err != nil {
+		t.Fatalf("NewWithPin without outputs: %v", err)
+	}
+	b := bb.(*bouncer)
+	var got []PressLength
+	if _, err := b.OnPress(func(l PressLength) { got = append(got, l) }); err != nil {
+		t.Fatalf("OnPress: %v", err)
+	}
+	b.publish(Event{Length: ShortPress})
+	out := make(chan PressLength, 1)
+	if err := b.AddOutput(out); err != nil {
+		t.Fatalf("AddOutput: %v", err)
+	}
+	if err := b.RemoveOutput(out); err != nil {
+		t.Fatalf("RemoveOutput of the last output: %v", err)
+	}
+	b.publish(Event{Length: LongPress})
+	if len(out) != 0 {
+		t.Error("a removed output was published to")
+	}
+	if !equalLengths(got, []PressLength{ShortPress, LongPress}) {
+		t.Errorf("OnPress got %v, want [ShortPress LongPress]", got)
+	}
+	if n := b.Stats().Presses[LongPress]; n != 1 {
+		t.Errorf("Stats counted %d LongPresses, want 1", n)
+	}
+	if _, err := NewWithEvents(machine.D2); err != nil {
+		t.Errorf("NewWithEvents without outputs: %v", err)
+	}
+}
+
+func benchmarkPublish(b *testing.B, outputs int) {
+	bb, err := NewWithPin(bouncertest.NewFakePin(true))
+	if err != nil {
+		b.Fatalf("NewWithPin: %v", err)
+	}
+	bnc := bb.(*bouncer)
+	chans := make([]chan PressLength, outputs)
+	for i := range chans {
+		chans[i] = make(chan PressLength, 1)
+		if err := bnc.AddOutput(chans[i]); err != nil {
+			b.Fatalf("AddOutput: %v", err)
+		}
+	}
+	e := Event{Length: ShortPress}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bnc.publish(e)
+		for _, ch := range chans {
+			<-ch
+		}
+	}
+}
+
+func BenchmarkPublishSingle(b *testing.B) {
+	benchmarkPublish(b, 1)
+}
+
+func BenchmarkPublishMulti(b *testing.B) {
+	benchmarkPublish(b, 4)
+}
+
+func TestPhaseClassifiesOnlyWithPhaseOutput(t *testing.T) {
+	calls := 0
+	h := newHarness(t, Config{Classify: func(d time.Duration) PressLength {
+		calls += 1
+		return LongPress
+	}})
+	h.press(100 * time.Millisecond)
+	h.expect(LongPress)
+	if calls != 1 {
+		t.Errorf("Classify called %d times without a phase output, want 1", calls)
+	}
+	phases := make(chan Event, 4)
+	if err := h.b.AddPhaseOutput(phases); err != nil {
+		t.Fatalf("AddPhaseOutput: %v", err)
+	}
+	h.press(100 * time.Millisecond)
+	h.expect(LongPress)
+	if len(phases) != 2 {
+		t.Fatalf("%d phases, want 2", len(phases))
+	}
+	if start, end := <-phases, <-phases; start.Length != PressStarted || end.Length != LongPress || end.Press != start.Press {
+		t.Errorf("phases %v & %v, want PressStarted & LongPress of the same press", start.Length, end.Length)
+	}
+}
+
+func TestKeypadAnalogPublishBounces(t *testing.T) {
+	for _, publish := range []bool{false, true} {
+		clock := newFakeClock()
+		cfg := Config{Debounce: 5 * time.Millisecond, PublishBounces: publish, Clock: clock}
+		keys := make(chan KeyEvent, 4)
+		k, err := NewKeypad([]machine.Pin{machine.D2}, []machine.Pin{machine.D3}, keys)
+		if err != nil {
+			t.Fatalf("NewKeypad: %v", err)
+		}
+		if err := k.cls.apply(cfg); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+		k.sample(0, true)
+		clock.advance(10 * time.Millisecond) // debounced, but shorter than Short
+		k.sample(0, false)
+		a, err := NewAnalogBouncer(machine.A0, []AnalogBand{{Min: 0, Max: 100}}, keys)
+		if err != nil {
+			t.Fatalf("NewAnalogBouncer: %v", err)
+		}
+		if err := a.cls.apply(cfg); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+		a.sample(0)
+		clock.advance(10 * time.Millisecond)
+		a.sample(-1)
+		want := 0
+		if publish {
+			want = 2
+		}
+		if len(keys) != want {
+			t.Errorf("PublishBounces %v: %d Bounces published, want %d", publish, len(keys), want)
+		}
+		for len(keys) > 0 {
+			if e := <-keys; e.Length != Bounce {
+				t.Errorf("published %v, want Bounce", e.Length)
+			}
+		}
+	}
+}
+
+func TestBufferedOutputDepth(t *testing.T) {
+	b, _, _ := newFake(t)
+	for _, depth := range []int{0, -1} {
+		if err := b.AddBufferedOutput(make(chan PressLength), depth); err == nil || err.Error() != ERROR_INVALID_DEPTH {
+			t.Errorf("AddBufferedOutput depth %d: %v, want %s", depth, err, ERROR_INVALID_DEPTH)
+		}
+	}
+	if err := b.AddBufferedOutput(make(chan PressLength), 1); err != nil {
+		t.Errorf("AddBufferedOutput depth 1: %v", err)
+	}
+}
+
+func TestSequenceInvalidTimeout(t *testing.T) {
+	b, _, _ := newFake(t)
+	for _, timeout := range []time.Duration{0, -time.Second} {
+		if _, err := NewSequence(b, []PressLength{ShortPress}, timeout, make(chan struct{}, 1)); err == nil || err.Error() != ERROR_INVALID_TIMEOUT {
+			t.Errorf("NewSequence timeout %v: %v, want %s", timeout, err, ERROR_INVALID_TIMEOUT)
+		}
+	}
+}
+
+// run with -race to check the systick subscriptions are safe to change while the relay is ticking
+func TestConfigureInParallel(t *testing.T) {
+	t.Cleanup(ResetSubscribers)
+	relayTicks(t)
+	const n = 32
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b, err := NewWithPin(bouncertest.NewFakePin(true))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := b.Configure(Config{}); err != nil {
+				t.Error(err)
+			}
+			if i%2 == 1 { // unsubscribing concurrently too
+				b.Stop()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := subscribers(); got != n/2 {
+		t.Errorf("%d systick subscribers, want %d", got, n/2)
+	}
+}
+
+func TestStalledConsumerDoesntBlockRelay(t *testing.T) {
+	t.Cleanup(ResetSubscribers)
+	stalled, _ := NewWithPin(bouncertest.NewFakePin(true)) // its RecognizeAndPublish never runs, so its tickerCh fills
+	if err := stalled.Configure(Config{}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	live, _ := NewWithPin(bouncertest.NewFakePin(true))
+	if err := live.Configure(Config{}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := make(chan struct{})
+	go DebounceContext(ctx, ticks)
+	deadline := time.After(time.Second)
+	for i := 0; i < 100; i++ {
+		select {
+		case ticks <- struct{}{}:
+		case <-deadline:
+			t.Fatalf("the relay blocked after %d ticks", i)
+		}
+		select {
+		case <-live.(*bouncer).tickerCh:
+		case <-deadline:
+			t.Fatalf("only %d ticks relayed past a stalled consumer", i)
+		}
+	}
+}
+
+func TestDoubleGap(t *testing.T) {
+	h := newHarness(t, Config{DoubleGap: 250 * time.Millisecond})
+	h.press(50 * time.Millisecond)
+	h.wait(100 * time.Millisecond) // the second click begins within DoubleGap
+	h.expect()
+	h.press(50 * time.Millisecond)
+	h.expect(DoubleClick)
+	h.wait(time.Second)
+	h.press(50 * time.Millisecond)
+	h.wait(300 * time.Millisecond) // DoubleGap elapses before the next click
+	h.expect(ShortPress)
+	h.press(50 * time.Millisecond)
+	h.wait(300 * time.Millisecond)
+	h.expect(ShortPress)
+}
+
+func TestRepeatsOverTwoSecondHold(t *testing.T) {
+	h := newHarness(t, Config{RepeatDelay: 500 * time.Millisecond, RepeatInterval: 100 * time.Millisecond})
+	h.press(2 * time.Second)
+	h.wait(10 * time.Millisecond)
+	repeats := 0
+	for _, l := range h.lengths() {
+		if l != Repeat {
+			t.Errorf("published %v during a repeating hold", l)
+		}
+		repeats += 1
+	}
+	if repeats != 16 { // at 500ms, then every 100ms up to & including 2s
+		t.Errorf("%d Repeats over a 2s hold, want 16", repeats)
+	}
+}
+
+func TestConfigureRejectsInversions(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"Long < Short":      {Short: 300 * time.Millisecond, Long: 200 * time.Millisecond},
+		"Long < default":    {Long: 10 * time.Millisecond},
+		"ExtraLong < Long":  {Long: 800 * time.Millisecond, ExtraLong: 700 * time.Millisecond},
+		"ExtraLong < Short": {ExtraLong: 20 * time.Millisecond},
+		"Tap >= Short":      {Tap: 22 * time.Millisecond},
+	} {
+		b, _, _ := newFake(t)
+		if err := b.Configure(cfg); err == nil || err.Error() != ERROR_INVALID_PRESSLENGTH {
+			t.Errorf("%s: Configure returned %v, want %s", name, err, ERROR_INVALID_PRESSLENGTH)
+		}
+		if got := b.Duration(LongPress); got != 500*time.Millisecond {
+			t.Errorf("%s: Duration(LongPress) = %v after a rejected Configure, want the default", name, got)
+		}
+	}
+}
+
+func TestMissedUpEdgeRecovers(t *testing.T) {
+	h := newHarness(t, Config{})
+	h.down()
+	h.wait(time.Second)
+	h.pin.level = true // released without an interrupt
+	h.wait(2*1971*time.Millisecond - time.Second)
+	h.expect()
+	h.wait(2 * time.Millisecond)
+	h.expect(Timeout)
+	h.expectFault(ERROR_MISSED_UP_EDGE)
+	if h.b.InProgress() {
+		t.Error("press still in progress after Timeout")
+	}
+	h.press(100 * time.Millisecond)
+	h.expect(ShortPress)
+}
+
+func TestConfigureTwiceSubscribesOnce(t *testing.T) {
+	b, _, _ := newFake(t)
+	for i := 0; i < 2; i++ {
+		if err := b.Configure(Config{}); err != nil {
+			t.Fatalf("Configure: %v", err)
+		}
+	}
+	if got := subscribers(); got != 1 {
+		t.Errorf("%d systick subscriptions after configuring twice, want 1", got)
+	}
+}
+
+func TestInvalidPin(t *testing.T) {
+	if _, err := New(machine.NoPin, make(chan PressLength)); err == nil || err.Error() != ERROR_INVALID_PIN {
+		t.Errorf("New(NoPin) returned %v, want %s", err, ERROR_INVALID_PIN)
+	}
+	if _, err := NewWithPin(nil, make(chan PressLength)); err == nil || err.Error() != ERROR_INVALID_PIN {
+		t.Errorf("NewWithPin(nil) returned %v, want %s", err, ERROR_INVALID_PIN)
+	}
+	if _, err := NewWithEvents(machine.NoPin); err == nil || err.Error() != ERROR_INVALID_PIN {
+		t.Errorf("NewWithEvents(NoPin) returned %v, want %s", err, ERROR_INVALID_PIN)
+	}
+}
+
+func TestThresholdBoundaries(t *testing.T) {
+	const ms = time.Millisecond
+	for _, c := range []struct {
+		exclusive bool
+		d         time.Duration
+		want      PressLength
+	}{
+		{false, 9 * ms, Bounce},
+		{false, 10 * ms, Tap},
+		{false, 21 * ms, Tap},
+		{false, 22 * ms, ShortPress},
+		{false, 499 * ms, ShortPress},
+		{false, 500 * ms, LongPress},
+		{false, 1970 * ms, LongPress},
+		{false, 1971 * ms, ExtraLongPress},
+		{true, 10 * ms, Bounce},
+		{true, 11 * ms, Tap},
+		{true, 22 * ms, Tap},
+		{true, 23 * ms, ShortPress},
+		{true, 500 * ms, ShortPress},
+		{true, 501 * ms, LongPress},
+		{true, 1971 * ms, LongPress},
+		{true, 1972 * ms, ExtraLongPress},
+	} {
+		h := newHarness(t, Config{Tap: 10 * ms, Debounce: 5 * ms, PublishBounces: true, ExclusiveBounds: c.exclusive})
+		h.press(c.d)
+		if got := h.lengths(); !equalLengths(got, []PressLength{c.want}) {
+			t.Errorf("ExclusiveBounds %v, %v press: published %v, want %v", c.exclusive, c.d, got, c.want)
+		}
+	}
+}
+
+func TestSubscribersOfDifferingSpeeds(t *testing.T) {
+	h := newHarness(t, Config{})
+	slow := make(chan PressLength) // received from only once the fast subscriber has its event
+	fast := make(chan PressLength, 1)
+	if err := h.b.AddReliableOutput(slow, time.Second); err != nil {
+		t.Fatalf("AddReliableOutput: %v", err)
+	}
+	if err := h.b.AddOutput(fast); err != nil {
+		t.Fatalf("AddOutput: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		h.b.publish(Event{Length: LongPress})
+		close(done)
+	}()
+	select {
+	case l := <-fast:
+		if l != LongPress {
+			t.Errorf("fast subscriber received %v, want LongPress", l)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("the fast subscriber waited on the slow one")
+	}
+	if l := <-slow; l != LongPress {
+		t.Errorf("slow subscriber received %v, want LongPress", l)
+	}
+	<-done
+	if got := h.b.Stats().Dropped; got != 0 {
+		t.Errorf("Dropped = %d, want 0", got)
+	}
+}
+
+func TestNoStaleBtnDown(t *testing.T) {
+	h := newHarness(t, Config{})
+	for _, d := range []time.Duration{30 * time.Millisecond, 600 * time.Millisecond, 100 * time.Millisecond, 2 * time.Second} {
+		h.clock.advance(300 * time.Microsecond) // edges between systicks
+		at := h.clock.Now()
+		h.press(d)
+		h.wait(400 * time.Millisecond) // beyond DoubleGap, were it set
+		events := h.published()
+		if len(events) != 1 {
+			t.Fatalf("%v press published %d events, want 1", d, len(events))
+		}
+		if e := events[0]; !e.At.Equal(at) || e.Duration != d {
+			t.Errorf("%v press published At %v & Duration %v, want %v & %v", d, e.At, e.Duration, at, d)
+		}
+		if !h.b.btnDown.IsZero() {
+			t.Errorf("btnDown %v left set after a %v press", h.b.btnDown, d)
+		}
+	}
+}
+
+func TestMinPressRejectsGhosts(t *testing.T) {
+	for _, publish := range []bool{false, true} {
+		h := newHarness(t, Config{Debounce: 5 * time.Millisecond, MinPress: 40 * time.Millisecond, PublishBounces: publish})
+		h.press(30 * time.Millisecond) // debounced & long enough for a ShortPress, but shorter than MinPress
+		if publish {
+			h.expect(Bounce)
+		} else {
+			h.expect()
+		}
+		if got := h.b.Stats().Rejected; got != 1 {
+			t.Errorf("PublishBounces %v: Rejected = %d, want 1", publish, got)
+		}
+		h.press(50 * time.Millisecond)
+		h.expect(ShortPress)
+	}
+}
+
+func TestTickPeriodDebounce(t *testing.T) {
+	for _, period := range []time.Duration{time.Millisecond, 10 * time.Millisecond} { // 1kHz & 100Hz
+		h := newHarness(t, Config{TickPeriod: period, Tap: time.Millisecond})
+		h.period = period
+		if got := h.b.TickPeriod(); got != period {
+			t.Errorf("TickPeriod() = %v, want %v", got, period)
+		}
+		h.down()
+		h.clock.advance(period / 2)
+		h.up() // within one TickPeriod: not believed
+		h.wait(3 * period)
+		h.expect()
+		if got := h.b.Stats().Rejected; got != 1 {
+			t.Errorf("%v: Rejected = %d, want 1", period, got)
+		}
+		h.press(period) // a full TickPeriod: believed
+		h.expect(Tap)
+		h.wait(100 * time.Millisecond)
+		h.press(30 * time.Millisecond)
+		h.expect(ShortPress)
+	}
+}
+
+func TestHysteresisDeadband(t *testing.T) {
+	h := newHarness(t, Config{NotifyThresholds: true, Hysteresis: 50 * time.Millisecond})
+	h.down()
+	h.wait(71 * time.Millisecond)
+	h.expect() // Short (22ms) is only crossed at 72ms
+	h.wait(time.Millisecond)
+	h.expect(HeldShortPress)
+	h.wait(477 * time.Millisecond)
+	h.expect() // in the deadband: Long (500ms) is only crossed at 550ms
+	h.wait(time.Millisecond)
+	h.expect(HeldLongPress)
+	h.up()
+	h.expect(LongPress)
+	h.wait(time.Second)
+	h.press(520 * time.Millisecond) // released in the deadband
+	h.expect(HeldShortPress, LongPress)
+}
+
+func TestDuplicateChannelSingleDelivery(t *testing.T) {
+	out := make(chan PressLength, 4)
+	bb, err := NewWithPin(bouncertest.NewFakePin(true), out, out)
+	if err != nil {
+		t.Fatalf("NewWithPin: %v", err)
+	}
+	b := bb.(*bouncer)
+	if err := b.AddOutput(out); err == nil || err.Error() != ERROR_DUPLICATE_OUTPUT {
+		t.Errorf("AddOutput of a duplicate returned %v, want %s", err, ERROR_DUPLICATE_OUTPUT)
+	}
+	b.publish(Event{Length: ShortPress})
+	if len(out) != 1 {
+		t.Errorf("a channel passed twice received %d events, want 1", len(out))
+	}
+}
+
+func TestBounceBand(t *testing.T) {
+	const ms = time.Millisecond
+	h := newHarness(t, Config{Debounce: 5 * ms})
+	h.down()
+	h.wait(4 * ms)
+	h.up() // below the debounce interval: not believed, & dropped as a glitch
+	h.wait(10 * ms)
+	h.expect()
+	if h.b.InProgress() {
+		t.Error("a glitch is still in progress")
+	}
+	h.press(5 * ms) // at the debounce interval: believed, but a Bounce, dropped
+	h.press(21 * ms)
+	h.expect()
+	if got := h.b.Stats().Rejected; got != 1 {
+		t.Errorf("Rejected = %d, want 1 (just the unbelieved up edge)", got)
+	}
+	h.wait(10 * ms)
+	h.press(22 * ms) // at Short
+	h.expect(ShortPress)
+
+	h = newHarness(t, Config{Debounce: 5 * ms, PublishBounces: true})
+	h.press(5 * ms)
+	h.wait(10 * ms)
+	h.press(21 * ms)
+	h.wait(10 * ms)
+	h.press(22 * ms)
+	h.expect(Bounce, Bounce, ShortPress)
+}
+
+func TestCooldown(t *testing.T) {
+	h := newHarness(t, Config{Cooldown: 200 * time.Millisecond})
+	h.press(50 * time.Millisecond)
+	h.wait(20 * time.Millisecond) // momentarily released, then the same tap again
+	h.press(50 * time.Millisecond)
+	h.expect(ShortPress)
+	h.wait(20 * time.Millisecond)
+	h.press(600 * time.Millisecond) // a different PressLength isn't suppressed
+	h.expect(LongPress)
+	h.wait(200 * time.Millisecond)
+	h.press(50 * time.Millisecond) // the cooldown has passed
+	h.expect(ShortPress)
+}
+
+func TestTickFlood(t *testing.T) {
+	b, pin, out := newFake(t)
+	if err := b.Configure(Config{TickBufferSize: 4, Debounce: 5 * time.Millisecond}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if got := cap(b.tickerCh); got != 4 {
+		t.Errorf("tickerCh buffers %d ticks, want 4", got)
+	}
+	go b.RecognizeAndPublish()
+	defer b.Stop()
+	relayTicks(t) // as fast as they'll be taken, so surplus ticks are dropped
+	pin.Press()
+	time.Sleep(100 * time.Millisecond)
+	pin.Release()
+	select {
+	case l := <-out:
+		if l != ShortPress {
+			t.Errorf("published %v under a flood of ticks, want ShortPress", l)
+		}
+	case <-time.After(time.Second):
+		t.Error("nothing published under a flood of ticks")
+	}
+}
+
+func TestTapOrHoldAtLong(t *testing.T) {
+	const ms = time.Millisecond
+	for _, c := range []struct {
+		exclusive bool
+		d         time.Duration
+		want      PressLength
+	}{
+		{false, 499 * ms, ShortPress},
+		{false, 500 * ms, LongPress},
+		{true, 500 * ms, ShortPress},
+		{true, 501 * ms, LongPress},
+		{false, 3 * time.Second, LongPress},
+	} {
+		h := newHarness(t, Config{TapOrHold: true, ExclusiveBounds: c.exclusive})
+		h.press(c.d)
+		h.wait(10 * ms)
+		if got := h.lengths(); !equalLengths(got, []PressLength{c.want}) {
+			t.Errorf("ExclusiveBounds %v, released at %v: published %v, want %v", c.exclusive, c.d, got, c.want)
+		}
+	}
+}
+
+func TestGroupMixedPolarity(t *testing.T) {
+	t.Cleanup(ResetSubscribers)
+	low, high := machine.D4, machine.D5 // wired active-low & active-high
+	out := make(chan GroupEvent, 4)
+	g, err := NewGroupPins([]GroupPin{{Pin: low}, {Pin: high, ActiveHigh: true}}, out)
+	if err != nil {
+		t.Fatalf("NewGroupPins: %v", err)
+	}
+	if err := g.Configure(Config{Debounce: 5 * time.Millisecond}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if !low.Get() || high.Get() { // pulled up & down respectively
+		t.Fatalf("idle levels %v & %v, want high & low", low.Get(), high.Get())
+	}
+	go g.RecognizeAndPublish()
+	for i, p := range []machine.Pin{low, high} {
+		p.Set(!p.Get())
+		if g.State(i) != Pressed {
+			t.Errorf("pin %d isn't Pressed once set to its active level", i)
+		}
+		time.Sleep(100 * time.Millisecond)
+		p.Set(!p.Get())
+		select {
+		case e := <-out:
+			if e.Index != i || e.Length != ShortPress {
+				t.Errorf("published %+v, want a ShortPress of pin %d", e, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("nothing published for pin %d", i)
+		}
+	}
+}
+
+func TestPressReleaseDebounceIndependent(t *testing.T) {
+	const ms = time.Millisecond
+	h := newHarness(t, Config{Debounce: 5 * ms}) // Debounce alone: up edges within a press
+	h.press(50 * ms)
+	h.press(30 * ms) // straight after a release, but without a ReleaseSettle, a down is believed
+	h.expect(ShortPress, ShortPress)
+	h.down()
+	h.wait(4 * ms)
+	h.up() // opening within Debounce isn't believed
+	h.down()
+	h.wait(50 * ms)
+	h.up()
+	h.expect(ShortPress)
+
+	h = newHarness(t, Config{ReleaseSettle: 30 * ms}) // ReleaseSettle alone: down edges after a release
+	h.press(50 * ms)
+	h.expect(ShortPress)
+	h.wait(10 * ms)
+	h.press(ms) // chatter within ReleaseSettle is rejected
+	h.wait(40 * ms)
+	h.expect()
+	h.press(50 * ms) // after ReleaseSettle
+	h.expect(ShortPress)
+	h.wait(10 * ms)
+	h.down() // rejected, but still down once ReleaseSettle has passed, so a press after all
+	h.wait(100 * ms)
+	h.up()
+	h.expect(ShortPress)
+}
+
+func TestBothPolarities(t *testing.T) {
+	for _, activeHigh := range []bool{false, true} {
+		for _, poll := range []bool{false, true} {
+			h := newHarness(t, Config{ActiveHigh: activeHigh, Poll: poll})
+			if h.b.State() != Released {
+				t.Errorf("ActiveHigh %v, Poll %v: idle pin isn't Released", activeHigh, poll)
+			}
+			if !h.b.isDown(activeHigh) || h.b.isDown(!activeHigh) {
+				t.Errorf("ActiveHigh %v: isDown disagrees with the polarity", activeHigh)
+			}
+			h.down()
+			h.wait(100 * time.Millisecond)
+			if h.b.State() != Pressed || !h.b.InProgress() {
+				t.Errorf("ActiveHigh %v, Poll %v: held button isn't Pressed & in progress", activeHigh, poll)
+			}
+			h.up()
+			h.wait(10 * time.Millisecond)
+			h.expect(ShortPress)
+		}
+	}
+}
+
+func TestStuckPublishedOnce(t *testing.T) {
+	h := newHarness(t, Config{StuckThreshold: 5 * time.Second, RepeatDelay: 500 * time.Millisecond, RepeatInterval: time.Second})
+	h.down() // & never released
+	h.wait(4999 * time.Millisecond)
+	for _, l := range h.lengths() {
+		if l != Repeat {
+			t.Errorf("published %v before StuckThreshold", l)
+		}
+	}
+	h.wait(time.Millisecond)
+	h.expect(Stuck)
+	h.expectFault(ERROR_STUCK)
+	h.wait(time.Minute)
+	h.expect() // once, & nothing more
+	h.up()
+	h.expect()
+	h.press(100 * time.Millisecond)
+	h.expect(ShortPress)
+}
+
+func TestCumulativeLongPressOnThreshold(t *testing.T) {
+	for _, c := range []struct {
+		cumulative, onThreshold bool
+		atThreshold, onRelease  []PressLength
+	}{
+		{false, false, nil, []PressLength{LongPress}},
+		{false, true, []PressLength{LongPress}, nil},
+		{true, false, nil, []PressLength{ShortPress, LongPress}},
+		{true, true, []PressLength{LongPress}, []PressLength{ShortPress}},
+	} {
+		h := newHarness(t, Config{Cumulative: c.cumulative, LongPressOnThreshold: c.onThreshold})
+		h.down()
+		h.wait(700 * time.Millisecond) // past Long
+		if got := h.lengths(); !equalLengths(got, c.atThreshold) {
+			t.Errorf("Cumulative %v, LongPressOnThreshold %v: %v while held, want %v", c.cumulative, c.onThreshold, got, c.atThreshold)
+		}
+		h.up()
+		if got := h.lengths(); !equalLengths(got, c.onRelease) {
+			t.Errorf("Cumulative %v, LongPressOnThreshold %v: %v on release, want %v", c.cumulative, c.onThreshold, got, c.onRelease)
+		}
+	}
+}
+
+func TestLongPressOnThresholdExtraLong(t *testing.T) {
+	for _, c := range []struct {
+		cumulative bool
+		onRelease  []PressLength
+	}{
+		{false, []PressLength{ExtraLongPress}},
+		{true, []PressLength{ShortPress, ExtraLongPress}},
+	} {
+		h := newHarness(t, Config{Cumulative: c.cumulative, LongPressOnThreshold: true})
+		h.down()
+		h.wait(2500 * time.Millisecond) // past ExtraLong
+		h.expect(LongPress)
+		h.up()
+		if got := h.lengths(); !equalLengths(got, c.onRelease) {
+			t.Errorf("Cumulative %v: %v on release, want %v", c.cumulative, got, c.onRelease)
+		}
+	}
+	h := newHarness(t, Config{TapOrHold: true}) // no ExtraLongPress, by design
+	h.down()
+	h.wait(2500 * time.Millisecond)
+	h.expect(LongPress)
+	h.up()
+	h.expect()
+}
+
+func TestInterPressGap(t *testing.T) {
+	h := newHarness(t, Config{InterPressGap: 100 * time.Millisecond})
+	h.press(50 * time.Millisecond)
+	h.expect(ShortPress)
+	h.wait(30 * time.Millisecond)
+	h.press(50 * time.Millisecond) // begins & ends within the gap: a finger resettling
+	h.wait(100 * time.Millisecond)
+	h.expect()
+	if got := h.b.Stats().Rejected; got != 1 {
+		t.Errorf("Rejected = %d, want 1", got)
+	}
+	h.press(50 * time.Millisecond) // after the gap
+	h.expect(ShortPress)
+}
+
+func TestPressLengthString(t *testing.T) {
+	for _, c := range []struct {
+		l    PressLength
+		want string
+	}{
+		{Bounce, "Bounce"},
+		{ShortPress, "ShortPress"},
+		{LongPress, "LongPress"},
+		{ExtraLongPress, "ExtraLongPress"},
+		{DoubleClick, "DoubleClick"},
+		{PressStarted, "PressStarted"},
+		{Repeat, "Repeat"},
+		{HeldShortPress, "HeldShortPress"},
+		{HeldLongPress, "HeldLongPress"},
+		{HeldExtraLongPress, "HeldExtraLongPress"},
+		{Timeout, "Timeout"},
+		{MultiClick, "MultiClick"},
+		{Tap, "Tap"},
+		{Armed, "Armed"},
+		{Fired, "Fired"},
+		{Aborted, "Aborted"},
+		{Stuck, "Stuck"},
+		{SwitchedOn, "SwitchedOn"},
+		{SwitchedOff, "SwitchedOff"},
+		{SwitchedOff + 1, "PressLength(" + strconv.Itoa(int(SwitchedOff)+1) + ")"},
+		{200, "PressLength(200)"},
+	} {
+		if got := c.l.String(); got != c.want {
+			t.Errorf("PressLength(%d).String() = %q, want %q", uint8(c.l), got, c.want)
+		}
+	}
+}
+
+func TestSmokeFakePin(t *testing.T) {
+	b, pin, out := newFake(t)
+	if err := b.Configure(Config{}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	go b.RecognizeAndPublish()
+	defer b.Stop()
+	tickEvery(t, time.Millisecond)
+	pin.Press()
+	time.Sleep(100 * time.Millisecond)
+	pin.Release()
+	expectPress(t, out, ShortPress)
+}
+
+func TestSmokeShimPin(t *testing.T) {
+	t.Cleanup(ResetSubscribers)
+	out := make(chan PressLength, 4)
+	bb, err := New(machine.D2, out)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := bb.Configure(Config{}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if !machine.D2.Get() {
+		t.Fatal("the shim pin doesn't idle high on its pullup")
+	}
+	go bb.RecognizeAndPublish()
+	defer bb.Stop()
+	tickEvery(t, time.Millisecond)
+	machine.D2.Low()
+	time.Sleep(100 * time.Millisecond)
+	machine.D2.High()
+	expectPress(t, out, ShortPress)
+}
+
+// run with -race to check reconfiguring doesn't race RecognizeAndPublish
+func TestConfigureWhileRunning(t *testing.T) {
+	b, pin, out := newFake(t)
+	if err := b.Configure(Config{}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	go b.RecognizeAndPublish()
+	defer b.Stop()
+	tickEvery(t, time.Millisecond)
+	pin.Press()
+	time.Sleep(50 * time.Millisecond)
+	cfg := Config{Long: 600 * time.Millisecond, RepeatDelay: time.Second, RepeatInterval: time.Second, NotifyThresholds: true, Debounce: 5 * time.Millisecond}
+	if err := b.Configure(cfg); err != nil { // mid-press
+		t.Fatalf("Configure while running: %v", err)
+	}
+	if got := b.Duration(LongPress); got != 600*time.Millisecond {
+		t.Errorf("Duration(LongPress) = %v after reconfiguring, want 600ms", got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	pin.Release()
+	for {
+		select {
+		case l := <-out:
+			if l == ShortPress {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no ShortPress after reconfiguring mid-press")
+		}
+	}
+}
+
+func TestSetDurationsFromNextPress(t *testing.T) {
+	h := newHarness(t, Config{NotifyThresholds: true})
+	h.down()
+	h.wait(600 * time.Millisecond)
+	if err := h.b.SetDurations(Config{Long: time.Second}); err != nil {
+		t.Fatalf("SetDurations: %v", err)
+	}
+	h.wait(100 * time.Millisecond)
+	h.up()
+	h.expect(HeldShortPress, HeldLongPress, LongPress) // by the durations as the press began
+	h.press(700 * time.Millisecond)
+	h.expect(HeldShortPress, ShortPress) // the next press has the new Long
+}
+
+func TestStopWithBlockedPriorityOutput(t *testing.T) {
+	b, pin, _ := newFake(t)
+	if err := b.AddPriorityOutput(make(chan PressLength)); err != nil { // never received from
+		t.Fatalf("AddPriorityOutput: %v", err)
+	}
+	if err := b.Configure(Config{}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	returned := make(chan struct{})
+	go func() {
+		b.RecognizeAndPublish()
+		close(returned)
+	}()
+	tickEvery(t, time.Millisecond)
+	pin.Press()
+	time.Sleep(50 * time.Millisecond)
+	pin.Release() // publishing blocks on the priority output
+	time.Sleep(50 * time.Millisecond)
+	b.Stop()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("RecognizeAndPublish didn't return after Stop, blocked on a priority output")
+	}
+}
+
+func TestGroupGlitchRecovers(t *testing.T) {
+	clock := newFakeClock()
+	out := make(chan GroupEvent, 4)
+	g, err := NewGroup([]machine.Pin{machine.D6}, out)
+	if err != nil {
+		t.Fatalf("NewGroup: %v", err)
+	}
+	if err := g.cls.apply(Config{Debounce: 5 * time.Millisecond, Clock: clock}); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	wait := func(d time.Duration) {
+		for end := clock.t.Add(d); clock.t.Before(end); {
+			clock.advance(time.Millisecond)
+			g.tick()
+		}
+	}
+	g.edge(0, false)
+	clock.advance(2 * time.Millisecond)
+	g.edge(0, true) // within the debounce interval: a glitch
+	wait(10 * time.Second)
+	if g.ticks[0] != 0 {
+		t.Error("the glitch is still in a sequence")
+	}
+	g.edge(0, false)
+	wait(100 * time.Millisecond)
+	g.edge(0, true)
+	select {
+	case e := <-out:
+		if e.Length != ShortPress {
+			t.Errorf("a 100ms press after a glitch published %v, want ShortPress", e.Length)
+		}
+	default:
+		t.Error("nothing published for a press after a glitch")
+	}
+}
+
+func TestWaitForPressSkipsUnconcluded(t *testing.T) {
+	h := newHarness(t, Config{ArmFire: true})
+	got := make(chan PressLength, 1)
+	go func() {
+		l, err := h.b.WaitForPress(time.Second)
+		if err != nil {
+			t.Errorf("WaitForPress: %v", err)
+		}
+		got <- l
+	}()
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		h.b.outMu.Lock()
+		subscribed = len(h.b.outputs) > 0
+		h.b.outMu.Unlock()
+	}
+	h.press(600 * time.Millisecond) // Armed at Long, then Fired on release
+	if l := <-got; l != Fired {
+		t.Errorf("WaitForPress returned %v, want Fired", l)
+	}
+}
+
+func TestSequenceIgnoresRepeats(t *testing.T) {
+	b, _, _ := newFake(t)
+	out := make(chan struct{}, 1)
+	s, err := NewSequence(b, []PressLength{ShortPress, LongPress}, time.Second, out)
+	if err != nil {
+		t.Fatalf("NewSequence: %v", err)
+	}
+	now := time.Unix(1000, 0)
+	for _, l := range []PressLength{ShortPress, PressStarted, Repeat, Repeat, Stuck, LongPress} {
+		s.press(l, now)
+		now = now.Add(100 * time.Millisecond)
+	}
+	if len(out) != 1 {
+		t.Error("Repeats mid-pattern broke the match")
+	}
+}
+
+func TestChordWindowAndCancel(t *testing.T) {
+	const ms = time.Millisecond
+	a, pinA, _ := newFake(t)
+	b, pinB, _ := newFake(t)
+	out := make(chan struct{}, 4)
+	c, err := NewChord(50*ms, []Bouncer{a, b}, out)
+	if err != nil {
+		t.Fatalf("NewChord: %v", err)
+	}
+	now := time.Unix(1000, 0)
+	step := func(d time.Duration) {
+		now = now.Add(d)
+		c.sample(now)
+	}
+	expect := func(what string, want int) {
+		t.Helper()
+		if len(out) != want {
+			t.Errorf("%s: published %d, want %d", what, len(out), want)
+		}
+		for len(out) > 0 {
+			<-out
+		}
+	}
+	releaseAll := func() {
+		pinA.Release()
+		pinB.Release()
+		step(10 * ms)
+	}
+
+	pinA.Press()
+	step(0)
+	pinB.Press()
+	step(60 * ms)
+	expect("second member outside the window", 0)
+	releaseAll()
+
+	pinA.Press()
+	step(0)
+	pinB.Press()
+	step(40 * ms)
+	step(10 * ms)
+	step(time.Second)
+	expect("second member within the window", 1) // & only once while held
+	releaseAll()
+
+	pinA.Press()
+	step(0)
+	step(60 * ms)
+	pinA.Release()
+	pinB.Press()
+	step(10 * ms)
+	expect("first member released before the second went down", 0)
+	pinA.Press()
+	step(20 * ms) // within the window of B, timed from A's new press rather than its first
+	expect("first member pressed again", 1)
+}
+
+func TestChordStop(t *testing.T) {
+	a, _, _ := newFake(t)
+	b, _, _ := newFake(t)
+	c, err := NewChord(50*time.Millisecond, []Bouncer{a, b}, make(chan struct{}, 1))
+	if err != nil {
+		t.Fatalf("NewChord: %v", err)
+	}
+	if got := subscribers(); got != 0 {
+		t.Fatalf("%d subscribed before Configure, want 0", got)
+	}
+	c.Configure(Config{Clock: newFakeClock()})
+	if got := subscribers(); got != 1 {
+		t.Fatalf("%d subscribed after Configure, want 1", got)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		c.RecognizeAndPublish()
+		close(stopped)
+	}()
+	c.Stop()
+	c.Stop() // a second Stop is harmless
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("RecognizeAndPublish didn't return after Stop")
+	}
+	if got := subscribers(); got != 0 {
+		t.Errorf("%d subscribed after Stop, want 0", got)
+	}
+}
@@ -0,0 +1,91 @@
+// bouncertest provides fakes for driving a bouncer without hardware.
+//
+// A FakePin stands in for the button's pin via bouncer.NewWithPin, and the systick is faked by
+// passing your own channel to bouncer.Debounce and sending to it whenever a tick should elapse.
+package bouncertest
+
+import (
+	"sync"
+
+	"machine"
+)
+
+// FakePin is a bouncer.InputPin whose level is set by the test
+type FakePin struct {
+	mu        sync.Mutex
+	level     bool
+	mode      machine.PinMode
+	change    machine.PinChange
+	interrupt func(machine.Pin)
+	err       error // returned by SetInterrupt when registering a handler
+}
+
+// NewFakePin returns a FakePin reading the given level; pass true for an idle button on a pullup
+func NewFakePin(level bool) *FakePin {
+	return &FakePin{level: level}
+}
+
+// Configure records the pin mode
+func (p *FakePin) Configure(cfg machine.PinConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mode = cfg.Mode
+}
+
+// Mode returns the mode last passed to Configure
+func (p *FakePin) Mode() machine.PinMode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mode
+}
+
+// Get returns the pin's current level
+func (p *FakePin) Get() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.level
+}
+
+// SetInterrupt records the interrupt handler to be called by Set; a nil callback clears it.
+// Registering a handler fails with the error given to FailInterrupt, if any, leaving the previous one in place
+func (p *FakePin) SetInterrupt(change machine.PinChange, callback func(machine.Pin)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if callback != nil && p.err != nil {
+		return p.err
+	}
+	p.change, p.interrupt = change, callback
+	return nil
+}
+
+// FailInterrupt makes SetInterrupt fail with err when registering a handler, as a pin without interrupt support
+// would; clearing a handler still succeeds. Pass nil to succeed again
+func (p *FakePin) FailInterrupt(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Set changes the pin's level, calling the interrupt handler (from the calling goroutine) if the
+// edge matches the change it was registered for
+func (p *FakePin) Set(level bool) {
+	p.mu.Lock()
+	prev := p.level
+	p.level = level
+	cb := p.interrupt
+	fire := (level && !prev && p.change&machine.PinRising != 0) || (!level && prev && p.change&machine.PinFalling != 0)
+	p.mu.Unlock()
+	if fire && cb != nil { // called without the lock, as the handler reads the pin
+		cb(machine.NoPin)
+	}
+}
+
+// Press sets the pin low, as an active-low button going down
+func (p *FakePin) Press() {
+	p.Set(false)
+}
+
+// Release sets the pin high, as an active-low button going up
+func (p *FakePin) Release() {
+	p.Set(true)
+}
@@ -0,0 +1,71 @@
+package bouncer
+
+// Button is an ergonomic layer over a bouncer, publishing each kind of press on its own channel so a select can
+// handle them without switching on PressLength. Each channel has a buffer of 1 & an event is dropped if it's
+// full, as with other outputs. Which channels ever receive depends on the bouncer's Config: DoubleClicked needs
+// a DoubleGap (or ClickGap, which MultiClicked also needs), Held needs NotifyThresholds & Repeated a RepeatInterval
+type Button struct {
+	Clicked          chan struct{} // a ShortPress, or a Tap
+	DoubleClicked    chan struct{} // a DoubleClick
+	MultiClicked     chan struct{} // a MultiClick, of three or more clicks
+	LongPressed      chan struct{} // a LongPress
+	ExtraLongPressed chan struct{} // an ExtraLongPress
+	Held             chan struct{} // the button, still held, has crossed the LongPress threshold
+	Repeated         chan struct{} // a Repeat, while the button is held
+
+	src Bouncer
+	id  int // of the OnPress callback on src
+}
+
+// NewButton returns a new Button (or error) publishing src's presses. Like Multiplex, it's driven by an OnPress
+// callback, so no goroutine is needed beyond src's RecognizeAndPublish; src's own outputs carry on as before
+func NewButton(src Bouncer) (*Button, error) {
+	b := &Button{
+		Clicked:          make(chan struct{}, 1),
+		DoubleClicked:    make(chan struct{}, 1),
+		MultiClicked:     make(chan struct{}, 1),
+		LongPressed:      make(chan struct{}, 1),
+		ExtraLongPressed: make(chan struct{}, 1),
+		Held:             make(chan struct{}, 1),
+		Repeated:         make(chan struct{}, 1),
+		src:              src,
+	}
+	id, err := src.OnPress(b.press)
+	if err != nil {
+		return nil, err
+	}
+	b.id = id
+	return b, nil
+}
+
+// Stop unsubscribes the Button from its bouncer
+func (b *Button) Stop() {
+	b.src.RemoveOnPress(b.id)
+}
+
+// press signals the channel for l, if it has one
+func (b *Button) press(l PressLength) {
+	var ch chan struct{}
+	switch l {
+	case ShortPress, Tap:
+		ch = b.Clicked
+	case DoubleClick:
+		ch = b.DoubleClicked
+	case MultiClick:
+		ch = b.MultiClicked
+	case LongPress:
+		ch = b.LongPressed
+	case ExtraLongPress:
+		ch = b.ExtraLongPressed
+	case HeldLongPress:
+		ch = b.Held
+	case Repeat:
+		ch = b.Repeated
+	default:
+		return
+	}
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
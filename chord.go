@@ -0,0 +1,117 @@
+package bouncer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	ERROR_CHORD_TOO_FEW_MEMBERS = "Chord needs at least two bouncers"
+)
+
+// Chord recognizes several buttons being held down together, e.g. for a reset combo. Each systick it
+// polls its members' State; once all are down, and the last went down within the window of the first,
+// it publishes once on its output channel(s). Releasing any member before the others are down cancels
+// the pending chord, and a chord isn't published again until a member is released & pressed again
+type Chord struct {
+	members  []Bouncer
+	window   time.Duration
+	downAt   []time.Time      // per member, when it was first seen down; zero while up
+	fired    bool             // the chord was published & no member has been released since
+	now      func() time.Time // time.Now, or Config.Clock's Now
+	done     chan struct{}    // closed by Stop to end RecognizeAndPublish
+	stopOnce sync.Once        // so Stop may be called more than once
+	tickerCh chan struct{}    // produced by sendTicks -> consumed by RecognizeAndPublish
+	outChans []chan struct{}  // produced by RecognizeAndPublish -> consumed by subscribers of this chord
+}
+
+// NewChord returns a new Chord (or error) of the given bouncers, which must all be down within window of
+// each other
+func NewChord(window time.Duration, members []Bouncer, outs ...chan struct{}) (*Chord, error) {
+	if len(members) < 2 {
+		return nil, errors.New(ERROR_CHORD_TOO_FEW_MEMBERS)
+	}
+	if len(outs) < 1 {
+		return nil, errors.New(ERROR_NO_OUTPUT_CHANNELS)
+	}
+	c := &Chord{
+		members:  make([]Bouncer, len(members)),
+		window:   window,
+		downAt:   make([]time.Time, len(members)),
+		now:      time.Now,
+		done:     make(chan struct{}),
+		tickerCh: make(chan struct{}, 1),
+	}
+	copy(c.members, members)
+	for i := range outs {
+		c.outChans = append(c.outChans, outs[i])
+	}
+	return c, nil
+}
+
+// Configure takes the Clock & TickSource from cfg (the rest of a Config is its members' business) & subscribes
+// the Chord to the systick relay; call it before starting RecognizeAndPublish
+func (c *Chord) Configure(cfg Config) {
+	c.now = time.Now
+	if cfg.Clock != nil {
+		c.now = cfg.Clock.Now
+	}
+	addSysTickConsumer(c.tickerCh, cfg.TickSource)
+}
+
+// Stop unsubscribes the Chord from the systick relay & ends its RecognizeAndPublish goroutine
+func (c *Chord) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+		removeSysTickConsumer(c.tickerCh)
+	})
+}
+
+// RecognizeAndPublish should be a goroutine; it samples the members on each systick & publishes the chord
+func (c *Chord) RecognizeAndPublish() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.tickerCh:
+			c.sample(c.now())
+		}
+	}
+}
+
+// sample records which members are down at now, publishing the chord if it's complete
+func (c *Chord) sample(now time.Time) {
+	all := true
+	first, last := now, time.Time{}
+	for i := range c.members {
+		if !c.members[i].Pressed() {
+			c.downAt[i] = time.Time{} // a release cancels any pending chord
+			all = false
+			continue
+		}
+		if c.downAt[i].IsZero() {
+			c.downAt[i] = now
+		}
+		if c.downAt[i].Before(first) {
+			first = c.downAt[i]
+		}
+		if c.downAt[i].After(last) {
+			last = c.downAt[i]
+		}
+	}
+	if !all {
+		c.fired = false
+		return
+	}
+	if c.fired || last.Sub(first) > c.window {
+		return
+	}
+	c.fired = true
+	for i := range c.outChans {
+		select {
+		case c.outChans[i] <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package bouncer
+
+import (
+	"errors"
+
+	"machine"
+)
+
+// Direction is a single detent of rotation of an Encoder
+type Direction int8
+
+const (
+	CounterClockwise Direction = -1
+	Clockwise        Direction = 1
+)
+
+// quadrature maps a previous & current 2-bit (A<<1|B) encoder state, as prev<<2|cur, to a quarter step;
+// transitions that skip a state (or don't move) count for nothing
+var quadrature = [16]int8{0, -1, 1, 0, 1, 0, 0, -1, -1, 0, 0, 1, 0, 1, -1, 0}
+
+// Encoder recognizes the rotation of a quadrature rotary encoder wired to two pins, which are sampled
+// on each systick from the same Debounce relay as the bouncers. A line's level is believed once it reads the
+// same on two consecutive systicks, so the systick must be fast compared to how quickly the knob is turned
+// (hundreds of Hz or more for a hand-turned knob). A Direction is published for each full quadrature cycle
+type Encoder struct {
+	pinA, pinB machine.Pin
+	raw        [2]bool          // last sample of each line
+	stable     [2]bool          // debounced level of each line
+	state      uint8            // last debounced A<<1|B
+	quarters   int8             // quarter steps accumulated since the last published Direction
+	tickerCh   chan struct{}    // produced by sendTicks -> consumed by RecognizeAndPublish
+	outChans   []chan Direction // produced by RecognizeAndPublish -> consumed by subscribers of this encoder
+}
+
+// NewEncoder returns a new Encoder (or error) with the given pins & channels
+func NewEncoder(a, b machine.Pin, outs ...chan Direction) (*Encoder, error) {
+	if !validPin(a) || !validPin(b) {
+		return nil, errors.New(ERROR_INVALID_PIN)
+	}
+	if len(outs) < 1 {
+		return nil, errors.New(ERROR_NO_OUTPUT_CHANNELS)
+	}
+	e := &Encoder{
+		pinA:     a,
+		pinB:     b,
+		tickerCh: make(chan struct{}, 1),
+	}
+	for i := range outs {
+		e.outChans = append(e.outChans, outs[i])
+	}
+	return e, nil
+}
+
+// Configure sets both pins to InputPullup, takes their resting state & subscribes to the systick relay
+func (e *Encoder) Configure() {
+	e.pinA.Configure(machine.PinConfig{Mode: machine.PinInputPullup})
+	e.pinB.Configure(machine.PinConfig{Mode: machine.PinInputPullup})
+	e.raw = [2]bool{e.pinA.Get(), e.pinB.Get()}
+	e.stable = e.raw
+	e.state = encoderState(e.stable)
+	addSysTickConsumer(e.tickerCh, "")
+}
+
+// RecognizeAndPublish should be a goroutine; it samples the pins on each systick & publishes each detent
+func (e *Encoder) RecognizeAndPublish() {
+	for {
+		select {
+		case <-e.tickerCh:
+			if d, ok := e.sample(e.pinA.Get(), e.pinB.Get()); ok {
+				e.publish(d)
+			}
+		}
+	}
+}
+
+// sample debounces a reading of both lines, returning a Direction if it completed a quadrature cycle
+func (e *Encoder) sample(a, b bool) (Direction, bool) {
+	for i, s := range [2]bool{a, b} {
+		if s == e.raw[i] { // read the same twice running
+			e.stable[i] = s
+		}
+		e.raw[i] = s
+	}
+	cur := encoderState(e.stable)
+	e.quarters += quadrature[e.state<<2|cur]
+	e.state = cur
+	switch {
+	case e.quarters >= 4:
+		e.quarters = 0
+		return Clockwise, true
+	case e.quarters <= -4:
+		e.quarters = 0
+		return CounterClockwise, true
+	}
+	return 0, false
+}
+
+// publish sends a Direction to all channels subscribed to this Encoder
+func (e *Encoder) publish(d Direction) {
+	for i := range e.outChans {
+		select {
+		case e.outChans[i] <- d:
+		default:
+		}
+	}
+}
+
+// encoderState packs the levels of lines A & B as A<<1|B
+func encoderState(lines [2]bool) uint8 {
+	var s uint8
+	if lines[0] {
+		s |= 2
+	}
+	if lines[1] {
+		s |= 1
+	}
+	return s
+}
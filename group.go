@@ -0,0 +1,194 @@
+package bouncer
+
+import (
+	"errors"
+	"time"
+
+	"machine"
+)
+
+const (
+	ERROR_NO_GROUP_PINS = "New group wasn't given any pins"
+)
+
+// GroupEvent is a PressLength recognized on the pin at Index in a Group's pins
+type GroupEvent struct {
+	Index  int
+	Length PressLength
+}
+
+// GroupPin is a pin of a Group made by NewGroupPins, with how its button is wired
+type GroupPin struct {
+	Pin        machine.Pin
+	ActiveHigh bool // as Config.ActiveHigh, for this pin alone
+}
+
+// groupEdge is a pin interrupt within a Group, identifying the pin by its index
+type groupEdge struct {
+	index int
+	level bool
+}
+
+// Group recognizes presses on several pins with a single goroutine. Every bouncer needs its own
+// RecognizeAndPublish goroutine, and every goroutine needs its own stack (sized by TinyGo's -stack-size);
+// a Group of N buttons costs one goroutine & one stack rather than N, which adds up on a constrained MCU.
+// A Group recognizes ShortPress, LongPress & ExtraLongPress using the durations, Debounce & ActiveHigh
+// (and Pull) in its Config; the other per-bouncer features (double clicks, repeats, etc.) aren't supported.
+// For a board mixing button polarities, NewGroupPins takes each pin's ActiveHigh in place of Config's
+type Group struct {
+	pins       []machine.Pin
+	activeHigh []bool            // per pin, if made by NewGroupPins; otherwise nil & Config.ActiveHigh applies to all
+	cls        *bouncer          // holds the Group's Config & classifies the presses of every pin
+	ticks      []int             // per pin, as ticks in bouncer.RecognizeAndPublish
+	btnDown    []time.Time       // per pin, as btnDown in bouncer.RecognizeAndPublish
+	lastDown   []bool            // per pin, whether the last edge handled was 'down', as lastEdgeDown in bouncer
+	tickerCh   chan struct{}     // produced by sendTicks -> consumed by RecognizeAndPublish, shared by all pins
+	isrChan    chan groupEdge    // produced by every pin's interrupt handler -> consumed by RecognizeAndPublish
+	outChans   []chan GroupEvent // produced by RecognizeAndPublish -> consumed by subscribers of this group's events
+}
+
+// NewGroup returns a new Group (or error) with the given pins & channels, with the same default durations as New
+func NewGroup(pins []machine.Pin, outs ...chan GroupEvent) (*Group, error) {
+	if len(pins) < 1 {
+		return nil, errors.New(ERROR_NO_GROUP_PINS)
+	}
+	for i := range pins {
+		if !validPin(pins[i]) {
+			return nil, errors.New(ERROR_INVALID_PIN)
+		}
+	}
+	if len(outs) < 1 {
+		return nil, errors.New(ERROR_NO_OUTPUT_CHANNELS)
+	}
+	g := &Group{
+		pins:     make([]machine.Pin, len(pins)),
+		cls:      newBouncer(machine.NoPin),
+		ticks:    make([]int, len(pins)),
+		btnDown:  make([]time.Time, len(pins)),
+		lastDown: make([]bool, len(pins)),
+		tickerCh: make(chan struct{}, 1),
+		isrChan:  make(chan groupEdge, 3*len(pins)), // Buffer interrupts during rapid bouncing on every pin
+	}
+	copy(g.pins, pins)
+	for i := range outs {
+		g.outChans = append(g.outChans, outs[i])
+	}
+	return g, nil
+}
+
+// NewGroupPins is like NewGroup, but each pin carries its own ActiveHigh, which Config.ActiveHigh doesn't override
+func NewGroupPins(pins []GroupPin, outs ...chan GroupEvent) (*Group, error) {
+	ps := make([]machine.Pin, len(pins))
+	for i := range pins {
+		ps[i] = pins[i].Pin
+	}
+	g, err := NewGroup(ps, outs...)
+	if err != nil {
+		return nil, err
+	}
+	g.activeHigh = make([]bool, len(pins))
+	for i := range pins {
+		g.activeHigh[i] = pins[i].ActiveHigh
+	}
+	return g, nil
+}
+
+// Configure applies cfg like Bouncer.Configure, then sets up each pin & its interrupt handler
+func (g *Group) Configure(cfg Config) error {
+	if err := g.cls.apply(cfg); err != nil {
+		return err
+	}
+	for i := range g.pins {
+		i := i
+		g.pins[i].Configure(machine.PinConfig{Mode: g.cls.pinModeFor(g.isActiveHigh(i))})
+		err := g.pins[i].SetInterrupt(machine.PinFalling|machine.PinRising, func(machine.Pin) {
+			select {
+			case g.isrChan <- groupEdge{index: i, level: g.pins[i].Get()}:
+			default:
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	addSysTickConsumer(g.tickerCh, g.cls.tickSource)
+	return nil
+}
+
+// RecognizeAndPublish should be a goroutine; it runs the same buttonDown -> buttonUp sequence as
+// bouncer.RecognizeAndPublish for every pin in the Group, publishing a GroupEvent for each press
+func (g *Group) RecognizeAndPublish() {
+	for {
+		select {
+		case <-g.tickerCh:
+			g.tick()
+		case edge := <-g.isrChan:
+			g.edge(edge.index, edge.level)
+		}
+	}
+}
+
+// tick counts a systick for each pin in a sequence, ending any that was a glitch as bouncer's tick does
+func (g *Group) tick() {
+	for i := range g.ticks {
+		if g.ticks[i] == 0 { // only pins in a sequence are listening
+			continue
+		}
+		if g.ticks[i] < maxTicks {
+			g.ticks[i] += 1
+		}
+		if !g.lastDown[i] && g.cls.debounced(g.ticks[i], g.btnDown[i]) { // its up edge came within the debounce interval
+			g.ticks[i] = 0
+			g.btnDown[i] = time.Time{}
+		}
+	}
+}
+
+// edge handles a level read from pin i by its interrupt
+func (g *Group) edge(i int, level bool) {
+	up := !g.isDown(i, level)
+	g.lastDown[i] = !up
+	switch {
+	case up && g.ticks[i] > 0 && g.cls.debounced(g.ticks[i], g.btnDown[i]): // sequence concluded
+		dur := g.cls.now().Sub(g.btnDown[i])
+		g.ticks[i] = 0
+		g.btnDown[i] = time.Time{}
+		if l := g.cls.recognize(dur); l != Bounce || g.cls.publishBounces { // too short to be a press
+			g.publish(GroupEvent{Index: i, Length: l})
+		}
+	case !up && g.ticks[i] == 0: // sequence began
+		g.ticks[i] = 1
+		g.btnDown[i] = g.cls.now()
+	} // otherwise ignore, as a single bouncer would
+}
+
+// State returns an on-demand measurement of the pin at index i as Pressed or Released
+func (g *Group) State(i int) ButtonState {
+	if g.isDown(i, g.pins[i].Get()) {
+		return Pressed
+	}
+	return Released
+}
+
+// isActiveHigh reports whether the button on the pin at index i pulls it high when pressed
+func (g *Group) isActiveHigh(i int) bool {
+	if g.activeHigh != nil {
+		return g.activeHigh[i]
+	}
+	return g.cls.activeHigh
+}
+
+// isDown reports whether the pin at index i reading level means its button is down, as bouncer.isDown
+func (g *Group) isDown(i int, level bool) bool {
+	return level == g.isActiveHigh(i)
+}
+
+// publish sends a GroupEvent to all channels subscribed to this Group
+func (g *Group) publish(e GroupEvent) {
+	for i := range g.outChans {
+		select {
+		case g.outChans[i] <- e:
+		default:
+		}
+	}
+}
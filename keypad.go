@@ -0,0 +1,134 @@
+package bouncer
+
+import (
+	"errors"
+	"time"
+
+	"machine"
+)
+
+const (
+	ERROR_NO_KEYPAD_PINS = "New keypad needs at least one row & one column pin"
+)
+
+// KeyEvent is a PressLength recognized on the key at Key, numbered row by row: row*len(cols) + col
+type KeyEvent struct {
+	Key    int
+	Length PressLength
+}
+
+// Keypad recognizes presses on a scanned matrix keypad, e.g. a 4x4 keypad wired as 4 row & 4 column pins.
+// Rows are driven low one at a time, a row per systick, & the columns read on the next systick (giving the
+// lines a full systick to settle), so each key is sampled once every len(rows) systicks; keys are debounced &
+// recognized from those samples as a Group's pins are, with the same Config. A matrix without a diode per key
+// ghosts: holding three keys at the corners of a rectangle makes the fourth corner read as pressed too, which
+// Keypad can't tell apart from a real press, so don't rely on more than two keys held at once
+type Keypad struct {
+	rows, cols []machine.Pin
+	cls        *bouncer        // holds the Keypad's Config & classifies the presses of every key
+	row        int             // the row currently driven low
+	ticks      []int           // per key, as ticks in bouncer.RecognizeAndPublish
+	btnDown    []time.Time     // per key, as btnDown in bouncer.RecognizeAndPublish
+	tickerCh   chan struct{}   // produced by sendTicks -> consumed by RecognizeAndPublish, which drives the scan
+	outChans   []chan KeyEvent // produced by RecognizeAndPublish -> consumed by subscribers of this keypad's events
+}
+
+// NewKeypad returns a new Keypad (or error) with the given row & column pins & channels, with the same
+// default durations as New
+func NewKeypad(rows, cols []machine.Pin, outs ...chan KeyEvent) (*Keypad, error) {
+	if len(rows) < 1 || len(cols) < 1 {
+		return nil, errors.New(ERROR_NO_KEYPAD_PINS)
+	}
+	for _, pins := range [][]machine.Pin{rows, cols} {
+		for i := range pins {
+			if !validPin(pins[i]) {
+				return nil, errors.New(ERROR_INVALID_PIN)
+			}
+		}
+	}
+	if len(outs) < 1 {
+		return nil, errors.New(ERROR_NO_OUTPUT_CHANNELS)
+	}
+	k := &Keypad{
+		rows:     make([]machine.Pin, len(rows)),
+		cols:     make([]machine.Pin, len(cols)),
+		cls:      newBouncer(machine.NoPin),
+		ticks:    make([]int, len(rows)*len(cols)),
+		btnDown:  make([]time.Time, len(rows)*len(cols)),
+		tickerCh: make(chan struct{}, 1),
+	}
+	copy(k.rows, rows)
+	copy(k.cols, cols)
+	for i := range outs {
+		k.outChans = append(k.outChans, outs[i])
+	}
+	return k, nil
+}
+
+// Configure applies cfg's durations & Debounce, sets the rows as outputs (all high but the first) & the
+// columns as InputPullup, and subscribes the Keypad to the systick relay. ActiveHigh & Pull don't apply,
+// as a pressed key always pulls its column low
+func (k *Keypad) Configure(cfg Config) error {
+	if err := k.cls.apply(cfg); err != nil {
+		return err
+	}
+	for i := range k.rows {
+		k.rows[i].Configure(machine.PinConfig{Mode: machine.PinOutput})
+		k.rows[i].High()
+	}
+	for i := range k.cols {
+		k.cols[i].Configure(machine.PinConfig{Mode: machine.PinInputPullup})
+	}
+	k.row = 0
+	k.rows[k.row].Low()
+	addSysTickConsumer(k.tickerCh, k.cls.tickSource)
+	return nil
+}
+
+// RecognizeAndPublish should be a goroutine; on each systick it reads the columns of the row driven low,
+// runs the same buttonDown -> buttonUp sequence as bouncer.RecognizeAndPublish for each of its keys, then
+// drives the next row low, publishing a KeyEvent for each press
+func (k *Keypad) RecognizeAndPublish() {
+	for {
+		select {
+		case <-k.tickerCh:
+			for i := range k.ticks {
+				if k.ticks[i] > 0 && k.ticks[i] < maxTicks { // only keys in a sequence are listening
+					k.ticks[i] += 1
+				}
+			}
+			for c := range k.cols {
+				k.sample(k.row*len(k.cols)+c, !k.cols[c].Get())
+			}
+			k.rows[k.row].High()
+			k.row = (k.row + 1) % len(k.rows)
+			k.rows[k.row].Low()
+		}
+	}
+}
+
+// sample handles a reading of the key at index i
+func (k *Keypad) sample(i int, down bool) {
+	switch {
+	case !down && k.ticks[i] > 0 && k.cls.debounced(k.ticks[i], k.btnDown[i]): // sequence concluded
+		dur := k.cls.now().Sub(k.btnDown[i])
+		k.ticks[i] = 0
+		k.btnDown[i] = time.Time{}
+		if l := k.cls.recognize(dur); l != Bounce || k.cls.publishBounces { // too short to be a press
+			k.publish(KeyEvent{Key: i, Length: l})
+		}
+	case down && k.ticks[i] == 0: // sequence began
+		k.ticks[i] = 1
+		k.btnDown[i] = k.cls.now()
+	} // otherwise ignore, as a single bouncer would
+}
+
+// publish sends a KeyEvent to all channels subscribed to this Keypad
+func (k *Keypad) publish(e KeyEvent) {
+	for i := range k.outChans {
+		select {
+		case k.outChans[i] <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,167 @@
+//go:build !tinygo
+
+// Package machine is a host shim of the parts of TinyGo's machine package that bouncer uses, so that the
+// package & its tests build with the standard Go toolchain; see go.host.work. Pins have no hardware behind
+// them: an output pin's Set (or High, Low) changes the level Get reads, and calls the pin's interrupt handler
+// if the edge matches the change it was registered for, so a test can drive a machine.Pin as a button would.
+// TinyGo always uses its own machine package, so hardware builds are unaffected
+package machine
+
+import "sync"
+
+// Pin is a GPIO pin, numbered as on the board
+type Pin uint8
+
+// NoPin is an invalid pin, as in TinyGo
+const NoPin = Pin(0xff)
+
+// The pins named on an Arduino-style board, for code written against one
+const (
+	D2 Pin = iota + 2
+	D3
+	D4
+	D5
+	D6
+	D7
+	D8
+	D9
+	D10
+	D11
+	D12
+	D13
+	A0
+	A1
+	A2
+	A3
+	A4
+	A5
+)
+
+type PinMode uint8
+
+const (
+	PinInput PinMode = iota
+	PinInputPullup
+	PinInputPulldown
+	PinOutput
+)
+
+type PinConfig struct {
+	Mode PinMode
+}
+
+type PinChange uint8
+
+const (
+	PinRising PinChange = 1 << iota
+	PinFalling
+	PinToggle = PinRising | PinFalling
+)
+
+// pin is the simulated state of a Pin
+type pin struct {
+	level     bool
+	mode      PinMode
+	change    PinChange
+	interrupt func(Pin)
+}
+
+var (
+	pinsMu sync.Mutex
+	pins   = map[Pin]*pin{}
+)
+
+// state returns the simulated state of p, creating it if need be; pinsMu must be held
+func (p Pin) state() *pin {
+	s, ok := pins[p]
+	if !ok {
+		s = &pin{}
+		pins[p] = s
+	}
+	return s
+}
+
+// Configure sets the pin's mode; a pullup makes it read high until it's Set otherwise
+func (p Pin) Configure(cfg PinConfig) {
+	pinsMu.Lock()
+	defer pinsMu.Unlock()
+	s := p.state()
+	s.mode = cfg.Mode
+	switch cfg.Mode {
+	case PinInputPullup:
+		s.level = true
+	case PinInputPulldown:
+		s.level = false
+	}
+}
+
+// Get returns the pin's current level
+func (p Pin) Get() bool {
+	pinsMu.Lock()
+	defer pinsMu.Unlock()
+	return p.state().level
+}
+
+// Set changes the pin's level, calling its interrupt handler (from the calling goroutine) if the edge
+// matches the change it was registered for
+func (p Pin) Set(high bool) {
+	pinsMu.Lock()
+	s := p.state()
+	prev := s.level
+	s.level = high
+	change, interrupt := s.change, s.interrupt
+	pinsMu.Unlock()
+	if interrupt == nil || prev == high {
+		return
+	}
+	if (high && change&PinRising != 0) || (!high && change&PinFalling != 0) {
+		interrupt(p)
+	}
+}
+
+// High sets the pin high
+func (p Pin) High() {
+	p.Set(true)
+}
+
+// Low sets the pin low
+func (p Pin) Low() {
+	p.Set(false)
+}
+
+// SetInterrupt registers the handler to be called by Set; a zero change or nil callback clears it
+func (p Pin) SetInterrupt(change PinChange, callback func(Pin)) error {
+	pinsMu.Lock()
+	defer pinsMu.Unlock()
+	s := p.state()
+	if change == 0 {
+		callback = nil
+	}
+	s.change, s.interrupt = change, callback
+	return nil
+}
+
+type ADC struct {
+	Pin Pin
+}
+
+type ADCConfig struct{}
+
+// InitADC does nothing on the host
+func InitADC() {}
+
+// Configure does nothing on the host; like atsamd21's & nrf's, it returns nothing
+func (a ADC) Configure(ADCConfig) {}
+
+// Get reads full scale if the ADC's pin is high & zero if it's low, as there's no voltage to measure
+func (a ADC) Get() uint16 {
+	if a.Pin.Get() {
+		return 0xffff
+	}
+	return 0
+}
+
+// CPUFrequency returns a nominal 48MHz
+func CPUFrequency() uint32 {
+	return 48000000
+}
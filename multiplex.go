@@ -0,0 +1,25 @@
+package bouncer
+
+// MultiplexedPress is a PressLength published by one of the bouncers passed to Multiplex
+type MultiplexedPress struct {
+	Source Bouncer
+	Press  PressLength
+}
+
+// Multiplex merges the events of several bouncers onto one channel, tagging each with the bouncer it came from,
+// so a single select can handle any button. Events are forwarded by an OnPress callback on each bouncer, so no
+// goroutines are started, and as with other outputs an event is dropped if the channel is full. A bouncer that
+// is stopped simply stops contributing; the channel stays open for the others
+func Multiplex(bouncers ...Bouncer) <-chan MultiplexedPress {
+	out := make(chan MultiplexedPress, len(bouncers))
+	for i := range bouncers {
+		src := bouncers[i]
+		src.OnPress(func(l PressLength) {
+			select {
+			case out <- MultiplexedPress{Source: src, Press: l}:
+			default:
+			}
+		})
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package bouncer
+
+import (
+	"time"
+
+	"machine"
+)
+
+// Sample is one systick of a recorded trace for Replay: the pin read Level, At that long into the trace
+type Sample struct {
+	At    time.Duration
+	Level bool
+}
+
+// replayPin is the InputPin of a bouncer driven by Replay, reading the level of the current Sample
+type replayPin struct {
+	level bool
+}
+
+func (p *replayPin) Configure(machine.PinConfig) {}
+
+func (p *replayPin) Get() bool {
+	return p.level
+}
+
+func (p *replayPin) SetInterrupt(machine.PinChange, func(machine.Pin)) error {
+	return nil
+}
+
+// Replay feeds a recorded trace through the same recognition as RecognizeAndPublish, configured by cfg, and
+// returns the PressLengths it would have published, without hardware, goroutines or the systick relay; e.g. to
+// pin down recognition of a troublesome trace in a regression test. Each Sample is treated as a systick on
+// which the pin was sampled, as in Poll (or Integrator) mode, and time is taken from the samples rather than
+// the clock. Clicks held back awaiting a DoubleClick are only published if the trace runs on past the gap
+func Replay(cfg Config, samples []Sample) ([]PressLength, error) {
+	pin := &replayPin{level: !cfg.ActiveHigh} // the button starts 'up'
+	b := newBouncer(pin)
+	if err := b.apply(cfg); err != nil {
+		return nil, err
+	}
+	start := time.Unix(0, 0) // any non-zero time will do, as zero means no press is in progress
+	now := start
+	b.now = func() time.Time { return now }
+	b.polledLevel = pin.level
+	var presses []PressLength
+	b.OnPress(func(l PressLength) { presses = append(presses, l) })
+	for i := range samples {
+		now = start.Add(samples[i].At)
+		pin.level = samples[i].Level
+		b.tick()
+		if b.integratorMax > 0 {
+			b.integrate(pin.level)
+		} else if pin.level != b.polledLevel {
+			b.polledLevel = pin.level
+			b.edge(pin.level)
+		}
+	}
+	return presses, nil
+}
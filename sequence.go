@@ -0,0 +1,103 @@
+package bouncer
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	ERROR_EMPTY_SEQUENCE  = "Sequence needs at least one PressLength"
+	ERROR_INVALID_TIMEOUT = "Timeout must be positive"
+)
+
+// Sequence recognizes a pattern of presses on one bouncer, e.g. ShortPress, ShortPress, LongPress to unlock
+// a feature. The whole pattern must be pressed within timeout of its first press; on a press that doesn't match
+// the next in the pattern (or comes too late), matching resumes from the latest presses that could still begin it,
+// so Short, Short, Short, Long matches Short, Short, Long.
+// Lengths that don't conclude a press (PressStarted, Repeat, the Held* threshold crossings & so on) are ignored
+type Sequence struct {
+	src      Bouncer
+	pattern  []PressLength
+	timeout  time.Duration
+	at       []time.Time // when each press of the pattern matched so far was published
+	id       int         // of the OnPress callback on src
+	outChans []chan struct{}
+}
+
+// NewSequence returns a new Sequence (or error) matching pattern on src's presses, publishing to the given
+// channels each time it's matched. Like Multiplex, it's driven by an OnPress callback, so no goroutine is needed
+func NewSequence(src Bouncer, pattern []PressLength, timeout time.Duration, outs ...chan struct{}) (*Sequence, error) {
+	if len(pattern) < 1 {
+		return nil, errors.New(ERROR_EMPTY_SEQUENCE)
+	}
+	if timeout <= 0 {
+		return nil, errors.New(ERROR_INVALID_TIMEOUT)
+	}
+	if len(outs) < 1 {
+		return nil, errors.New(ERROR_NO_OUTPUT_CHANNELS)
+	}
+	s := &Sequence{
+		src:     src,
+		pattern: make([]PressLength, len(pattern)),
+		timeout: timeout,
+	}
+	copy(s.pattern, pattern)
+	for i := range outs {
+		s.outChans = append(s.outChans, outs[i])
+	}
+	id, err := src.OnPress(func(l PressLength) { s.press(l, time.Now()) })
+	if err != nil {
+		return nil, err
+	}
+	s.id = id
+	return s, nil
+}
+
+// Stop unsubscribes the Sequence from its bouncer
+func (s *Sequence) Stop() {
+	s.src.RemoveOnPress(s.id)
+}
+
+// press advances the match with a press published at now, publishing the Sequence if it's complete
+func (s *Sequence) press(l PressLength, now time.Time) {
+	if !concludesPress(l) {
+		return
+	}
+	presses := append(append([]PressLength{}, s.pattern[:len(s.at)]...), l)
+	at := append(s.at, now)
+	start := 0
+	for ; start < len(presses); start++ { // drop the oldest presses until the rest could begin the pattern in time
+		if now.Sub(at[start]) <= s.timeout && s.begins(presses[start:]) {
+			break
+		}
+	}
+	s.at = append(s.at[:0], at[start:]...)
+	if len(s.at) < len(s.pattern) {
+		return
+	}
+	s.at = s.at[:0]
+	s.publish()
+}
+
+// begins reports whether presses are the beginning of the pattern
+func (s *Sequence) begins(presses []PressLength) bool {
+	if len(presses) > len(s.pattern) {
+		return false
+	}
+	for i := range presses {
+		if presses[i] != s.pattern[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// publish signals all channels subscribed to this Sequence
+func (s *Sequence) publish() {
+	for i := range s.outChans {
+		select {
+		case s.outChans[i] <- struct{}{}:
+		default:
+		}
+	}
+}